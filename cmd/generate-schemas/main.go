@@ -0,0 +1,79 @@
+// Command generate-schemas writes the validation schemas described by the
+// validate tags of the models, one YAML file per model. Models without tags
+// are skipped. The schemas of the schemas directory that set messages or
+// constraints, which tags cannot express, are written by hand.
+//
+//	go run ./cmd/generate-schemas -out schemas/generated
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/model"
+	"github.com/arturoeanton/go-struc2fiber/pkg/validator"
+)
+
+// models are the types schemas are generated for, by file name
+var models = map[string]interface{}{
+	"internal_user":  model.InternalUser{},
+	"skill":          model.Skill{},
+	"connect":        model.Connect{},
+	"day_off":        model.DayOff{},
+	"apartment":      model.Apartment{},
+	"checkin":        model.Checkin{},
+	"event":          model.Event{},
+	"extended_props": model.ExtendedProps{},
+}
+
+func main() {
+	out := flag.String("out", "schemas/generated", "directory the schemas are written to")
+	flag.Parse()
+
+	paths, err := generate(*out)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	for _, path := range paths {
+		fmt.Println("Generated", path)
+	}
+}
+
+// generate writes the schema of each model with tags to dir, returning the
+// paths written in name order
+func generate(dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(models))
+	for name := range models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	paths := []string{}
+	for _, name := range names {
+		schema, err := validator.SchemaFromStruct(models[name])
+		if err != nil {
+			return nil, err
+		}
+		if len(schema.Rules) == 0 {
+			continue
+		}
+		content, err := validator.SchemaToYAML(schema)
+		if err != nil {
+			return nil, err
+		}
+		path := filepath.Join(dir, name+".yaml")
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/validator"
+)
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	paths, err := generate(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, path := range paths {
+		names = append(names, filepath.Base(path))
+	}
+	if want := []string{"day_off.yaml", "internal_user.yaml", "skill.yaml"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("generated %v, want %v", names, want)
+	}
+
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			got, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The schemas shipped are those generated from the current tags
+			shipped, err := os.ReadFile(filepath.Join("../../schemas/generated", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(shipped) {
+				t.Fatalf("schemas/generated/%s is stale, run go run ./cmd/generate-schemas:\n%s", name, got)
+			}
+			if err := validator.NewStructValidator().LoadSchemaFromFile(filepath.Join(dir, name)); err != nil {
+				t.Fatalf("generated schema does not load: %v", err)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"github.com/arturoeanton/go-struc2fiber/pkg/commons"
+	"github.com/arturoeanton/go-struc2fiber/pkg/handlers"
 	"github.com/arturoeanton/go-struc2fiber/pkg/model"
 	"github.com/arturoeanton/go-struc2fiber/pkg/repositories"
 	"github.com/arturoeanton/go-struc2fiber/pkg/web"
@@ -24,10 +25,48 @@ func main() {
 	}
 	repositories.DB = db
 
-	app := fiber.New()
+	app := fiber.New(fiber.Config{
+		ErrorHandler: handlers.ProblemErrorHandler,
+	})
 
-	web.RegisterCRUD(app, "internal_users", model.InternalUser{}, "schemas/internal_users.yaml")
-	web.RegisterCRUD(app, "skill", model.Skill{}, "schemas/create_skill.yaml", "schemas/update_skill.yaml")
+	web.RegisterCRUDWithOptions(app, "internal_users", model.InternalUser{}, web.CRUDOptions{
+		CreateSchema:  "schemas/internal_users.yaml",
+		Transactional: true,
+		Includes:      []string{"skills", "connects", "day_offs", "checkins_of_agent.apartment"},
+		Associations:  []string{"skills", "day_offs"},
+		AssociationSchemas: map[string]string{
+			"skills":   "schemas/generated/skill.yaml",
+			"day_offs": "schemas/day_off.yaml",
+		},
+	})
+	web.RegisterCRUDWithOptions(app, "skill", model.Skill{}, web.CRUDOptions{
+		CreateSchema: "schemas/generated/skill.yaml",
+		UpdateSchema: "schemas/update_skill.yaml",
+		Filterable:   []string{"user_id", "name", "value"},
+		Sortable:     []string{"name", "value"},
+	})
+	web.RegisterCRUDWithOptions(app, "day_off", model.DayOff{}, web.CRUDOptions{
+		CreateSchema: "schemas/day_off.yaml",
+		Filterable:   []string{"user_id", "start", "end"},
+		Sortable:     []string{"start", "end"},
+	})
+	web.RegisterNestedCRUD(app, "internal_users", model.InternalUser{}, "skills", model.Skill{}, web.CRUDOptions{
+		CreateSchema: "schemas/generated/skill.yaml",
+		UpdateSchema: "schemas/update_skill.yaml",
+		Filterable:   []string{"name", "value"},
+		Sortable:     []string{"name", "value"},
+	})
+	web.RegisterNestedCRUD(app, "internal_users", model.InternalUser{}, "day_offs", model.DayOff{}, web.CRUDOptions{
+		CreateSchema: "schemas/day_off.yaml",
+		Sortable:     []string{"start", "end"},
+	})
+	web.RegisterCRUDWithOptions(app, "apartment", model.Apartment{}, web.CRUDOptions{
+		Operations: web.ReadOnlyOperations,
+	})
+
+	web.RegisterDocs(app, web.DocsOptions{
+		Info: web.OpenAPIInfo{Title: "go-struc2fiber API", Version: "1.0.0"},
+	})
 
 	app.Get("/", func(c *fiber.Ctx) error {
 		return c.SendString("Hello, World!")
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/repositories"
+	"github.com/arturoeanton/go-struc2fiber/pkg/validator"
+)
+
+// association is an association written along with the items
+type association struct {
+	*repositories.Association
+	// validator checks each child, nil when children are not validated
+	validator *validator.StructValidator
+}
+
+// SetAssociations sets the has one and has many associations, by JSON name,
+// written along with the items: the children sent replace the stored ones.
+// Each child is checked with the validator of its association when not nil.
+func (h *Handler[T]) SetAssociations(validators map[string]*validator.StructValidator) error {
+	names := make([]string, 0, len(validators))
+	for name := range validators {
+		names = append(names, name)
+	}
+	resolved, err := repositories.Associations[T](names...)
+	if err != nil {
+		return fmt.Errorf("%s: %v", h.Name(), err)
+	}
+
+	associations := []*association{}
+	fieldNames := []string{}
+	for _, a := range resolved {
+		associations = append(associations, &association{Association: a, validator: validators[a.JSONName]})
+		fieldNames = append(fieldNames, a.Name)
+	}
+	if err := h.service.SetAssociations(fieldNames...); err != nil {
+		return err
+	}
+	h.associations = associations
+	return nil
+}
+
+// validateAssociations checks the children sent in item, naming the fields
+// like "skills[0].name". Their foreign key is skipped, as it is only set
+// when they are saved.
+func (h *Handler[T]) validateAssociations(ctx context.Context, item *T) validator.ValidationErrors {
+	errs := validator.ValidationErrors{}
+	val := reflect.ValueOf(item).Elem()
+	for _, a := range h.associations {
+		if a.validator == nil {
+			continue
+		}
+		fields := []string{}
+		for name := range a.validator.GetRules() {
+			if name != a.ForeignKey {
+				fields = append(fields, name)
+			}
+		}
+		check := func(name string, child reflect.Value) {
+			if child.Kind() != reflect.Ptr {
+				child = child.Addr()
+			} else if child.IsNil() {
+				return
+			}
+			if valid, childErrs := a.validator.ValidateStructPartialContext(ctx, child.Interface(), fields); !valid {
+				errs = append(errs, childErrs.Prefixed(name)...)
+			}
+		}
+
+		field := val.FieldByName(a.Name)
+		switch field.Kind() {
+		case reflect.Slice:
+			for i := 0; i < field.Len(); i++ {
+				check(fmt.Sprintf("%s[%d]", a.JSONName, i), field.Index(i))
+			}
+		case reflect.Ptr:
+			check(a.JSONName, field)
+		default:
+			if !field.IsZero() {
+				check(a.JSONName, field)
+			}
+		}
+	}
+	return errs
+}
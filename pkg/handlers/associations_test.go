@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/model"
+	"github.com/arturoeanton/go-struc2fiber/pkg/validator"
+	fiber "github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// storedSkills returns the name of each stored skill by owner ID
+func storedSkills(t *testing.T, db *gorm.DB) map[int64][]string {
+	t.Helper()
+	var rows []model.Skill
+	if err := db.Order("id").Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	result := map[int64][]string{}
+	for _, row := range rows {
+		result[row.UserID] = append(result[row.UserID], row.Name)
+	}
+	return result
+}
+
+func TestWriteAssociations(t *testing.T) {
+	skillValidator := validator.NewStructValidator()
+	err := skillValidator.LoadSchemaFromYAML(`
+name: Skill
+rules:
+  - field: name
+    type: string
+    minLength: 2
+  - field: user_id
+    type: integer
+    min: 1
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		want   map[int64][]string
+	}{
+		{"upsert", `{"username":"ana","skills":[{"id":1,"name":"golang","value":40},{"name":"rust"}]}`, fiber.StatusNoContent,
+			map[int64][]string{1: {"golang", "rust"}, 2: {"c"}}},
+		{"omitted children deleted", `{"username":"ana","skills":[{"id":2,"name":"zig"}]}`, fiber.StatusNoContent,
+			map[int64][]string{1: {"zig"}, 2: {"c"}}},
+		{"empty array clears", `{"username":"ana","skills":[]}`, fiber.StatusNoContent,
+			map[int64][]string{2: {"c"}}},
+		{"omitted key untouched", `{"username":"ana"}`, fiber.StatusNoContent,
+			map[int64][]string{1: {"go", "zig"}, 2: {"c"}}},
+		// Skill 3 belongs to bob
+		{"child of another parent", `{"username":"ana","skills":[{"id":3,"name":"cpp"}]}`, fiber.StatusBadRequest,
+			map[int64][]string{1: {"go", "zig"}, 2: {"c"}}},
+		// The foreign key of the children is not validated, as it is set when saved
+		{"invalid child", `{"username":"ana","skills":[{"id":1,"name":"go"},{"name":"r"}]}`, fiber.StatusUnprocessableEntity,
+			map[int64][]string{1: {"go", "zig"}, 2: {"c"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t,
+				&model.InternalUser{Username: "ana", Email: "ana@example.com"},
+				&model.InternalUser{Username: "bob", Email: "bob@example.com"},
+				&model.Skill{UserID: 1, Name: "go", Value: 30},
+				&model.Skill{UserID: 1, Name: "zig", Value: 10},
+				&model.Skill{UserID: 2, Name: "c", Value: 20},
+			)
+			h := NewHandler[model.InternalUser]()
+			h.SetName("internal_users")
+			if err := h.SetAssociations(map[string]*validator.StructValidator{"skills": skillValidator}); err != nil {
+				t.Fatal(err)
+			}
+			app := fiber.New()
+			app.Put("/internal_users/:id", h.FxUpdate())
+
+			resp, body := send(t, app, "PUT", "/internal_users/1", tt.body)
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.status, body)
+			}
+			if tt.status == fiber.StatusUnprocessableEntity && !strings.Contains(body, `"field":"skills[1].name"`) {
+				t.Errorf("body = %s, want the error of skills[1].name", body)
+			}
+			if got := storedSkills(t, db); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("skills = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateAssociations(t *testing.T) {
+	db := openTestDB(t)
+	h := NewHandler[model.InternalUser]()
+	h.SetName("internal_users")
+	if err := h.SetAssociations(map[string]*validator.StructValidator{"skills": nil}); err != nil {
+		t.Fatal(err)
+	}
+	app := fiber.New()
+	app.Post("/internal_users", h.FxCreate())
+
+	resp, body := send(t, app, "POST", "/internal_users", `{"username":"ana","skills":[{"name":"go"},{"name":"c"}]}`)
+	if resp.StatusCode != fiber.StatusOK || body != "1" {
+		t.Fatalf("got %d %s", resp.StatusCode, body)
+	}
+	want := map[int64][]string{1: {"go", "c"}}
+	if got := storedSkills(t, db); !reflect.DeepEqual(got, want) {
+		t.Fatalf("skills = %v, want %v", got, want)
+	}
+
+	if err := h.SetAssociations(map[string]*validator.StructValidator{"unknown": nil}); err == nil {
+		t.Error("SetAssociations accepted an unknown association")
+	}
+}
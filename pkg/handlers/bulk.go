@@ -0,0 +1,415 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/repositories"
+	"github.com/arturoeanton/go-struc2fiber/pkg/validator"
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+// BulkMode selects how a bulk request handles the items failing, through ?mode=
+type BulkMode string
+
+const (
+	// BulkAtomic writes every item or none of them, the default
+	BulkAtomic BulkMode = "atomic"
+	// BulkBestEffort writes the items it can and reports the others
+	BulkBestEffort BulkMode = "best_effort"
+)
+
+var (
+	// MaxBulkItems caps the number of items of a bulk request
+	MaxBulkItems = 1000
+	// BulkBatchSize is the number of items written per statement by bulk requests
+	BulkBatchSize = 100
+)
+
+// BulkResult is the body of the responses to bulk requests
+type BulkResult struct {
+	Mode      BulkMode   `json:"mode"`
+	Succeeded int        `json:"succeeded"`
+	Failed    int        `json:"failed"`
+	Items     []BulkItem `json:"items"`
+}
+
+// BulkItem reports the outcome of one item of a bulk request, in request
+// order. Failures are described like the problems of single item requests,
+// items not written because another one failed have status 424.
+type BulkItem struct {
+	Index  int            `json:"index"`
+	ID     interface{}    `json:"id,omitempty"`
+	Status int            `json:"status"`
+	Code   string         `json:"code,omitempty"`
+	Detail string         `json:"detail,omitempty"`
+	Errors []ProblemField `json:"errors,omitempty"`
+}
+
+// bulk tracks the items of a bulk request
+type bulk struct {
+	mode  BulkMode
+	items []BulkItem
+}
+
+func newBulk(mode BulkMode, n int) *bulk {
+	b := &bulk{mode: mode, items: make([]BulkItem, n)}
+	for i := range b.items {
+		b.items[i].Index = i
+	}
+	return b
+}
+
+// fail records the problem of item i
+func (b *bulk) fail(i int, p *Problem) {
+	item := &b.items[i]
+	item.Status = p.Status
+	item.Code = p.Code
+	item.Detail = p.Detail
+	item.Errors = p.Errors
+}
+
+// pending returns the indexes of the items without outcome yet
+func (b *bulk) pending() []int {
+	indexes := []int{}
+	for i, item := range b.items {
+		if item.Status == 0 {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// send writes the outcome of the request: 200 when every item succeeded,
+// 207 when only some did and the status of the first failure otherwise
+func (b *bulk) send(c *fiber.Ctx) error {
+	result := BulkResult{Mode: b.mode, Items: b.items}
+	status := 0
+	for i := range b.items {
+		item := &b.items[i]
+		if item.Status == 0 {
+			item.Status = http.StatusFailedDependency
+			item.Detail = "Not written, another item failed"
+		}
+		if item.Status < http.StatusBadRequest {
+			result.Succeeded++
+			continue
+		}
+		result.Failed++
+		if status == 0 && item.Status != http.StatusFailedDependency {
+			status = item.Status
+		}
+	}
+	switch {
+	case result.Failed == 0:
+		status = http.StatusOK
+	case result.Succeeded > 0:
+		status = http.StatusMultiStatus
+	}
+	return c.Status(status).JSON(result)
+}
+
+// parseBulkMode reads ?mode=atomic|best_effort from the request
+func parseBulkMode(c *fiber.Ctx) (BulkMode, error) {
+	switch mode := BulkMode(c.Query("mode", string(BulkAtomic))); mode {
+	case BulkAtomic, BulkBestEffort:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("mode must be %s or %s", BulkAtomic, BulkBestEffort)
+	}
+}
+
+// decodeBulk splits a JSON array body into its items
+func decodeBulk(body []byte) ([]json.RawMessage, error) {
+	var raws []json.RawMessage
+	if err := json.Unmarshal(body, &raws); err != nil {
+		return nil, fmt.Errorf("body must be a JSON array: %v", err)
+	}
+	if len(raws) == 0 {
+		return nil, errors.New("body has no items")
+	}
+	if len(raws) > MaxBulkItems {
+		return nil, fmt.Errorf("at most %d items are allowed per request", MaxBulkItems)
+	}
+	return raws, nil
+}
+
+// errBulkFailed rolls back the transaction of an atomic bulk request once
+// an item failed
+var errBulkFailed = errors.New("bulk item failed")
+
+// writeBulk writes the pending items with write, in batches of
+// BulkBatchSize, and records them with status. Each batch runs in its own
+// transaction, a savepoint within an enclosing one, and a failed batch is
+// retried item by item to report the failing ones like the items failing
+// validation. In atomic mode nothing is written once an item failed: the
+// batches run in one transaction rolled back on the first failure, leaving
+// the other items with status 424.
+func (h *Handler[T]) writeBulk(c *fiber.Ctx, b *bulk, write func(ctx context.Context, indexes []int) error, status int) error {
+	pending := b.pending()
+	batches := [][]int{}
+	for start := 0; start < len(pending); start += BulkBatchSize {
+		end := start + BulkBatchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		batches = append(batches, pending[start:end])
+	}
+
+	if b.mode == BulkBestEffort {
+		for _, batch := range batches {
+			h.writeBatch(c.UserContext(), c, b, write, batch, status)
+		}
+		return nil
+	}
+
+	if len(pending) < len(b.items) {
+		return nil
+	}
+	err := repositories.WithTx(c.UserContext(), func(ctx context.Context) error {
+		for _, batch := range batches {
+			if !h.writeBatch(ctx, c, b, write, batch, status) {
+				return errBulkFailed
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errBulkFailed) {
+		// The items written were rolled back
+		for i := range b.items {
+			if b.items[i].Status == status {
+				b.items[i].Status = 0
+			}
+		}
+		return nil
+	}
+	return err
+}
+
+// writeBatch writes the items of batch in a transaction of ctx, retrying
+// them one by one when it fails to record the failing ones. It reports
+// whether every item was written.
+func (h *Handler[T]) writeBatch(ctx context.Context, c *fiber.Ctx, b *bulk, write func(ctx context.Context, indexes []int) error, batch []int, status int) bool {
+	run := func(indexes []int) error {
+		return repositories.WithTx(ctx, func(ctx context.Context) error {
+			return write(ctx, indexes)
+		})
+	}
+	if err := run(batch); err == nil {
+		for _, i := range batch {
+			b.items[i].Status = status
+		}
+		return true
+	}
+	written := true
+	for _, i := range batch {
+		if err := run([]int{i}); err != nil {
+			b.fail(i, h.problem(c, err))
+			written = false
+			continue
+		}
+		b.items[i].Status = status
+	}
+	return written
+}
+
+// FxBulkCreate creates the items of a JSON array body, see BulkMode. Each
+// item is validated on its own and the valid ones are inserted in batches.
+func (h *Handler[T]) FxBulkCreate(vals ...*validator.StructValidator) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		mode, err := parseBulkMode(c)
+		if err != nil {
+			return h.sendError(c, badInput(err))
+		}
+		parent, err := h.parentOf(c)
+		if err != nil {
+			return h.sendError(c, err)
+		}
+		raws, err := decodeBulk(c.Body())
+		if err != nil {
+			return h.sendError(c, badInput(err))
+		}
+		var v *validator.StructValidator
+		if len(vals) > 0 {
+			v = vals[0]
+		}
+
+		b := newBulk(mode, len(raws))
+		items := make([]*T, len(raws))
+		for i, raw := range raws {
+			item := new(T)
+			if err := json.Unmarshal(raw, item); err != nil {
+				b.fail(i, h.problem(c, badInput(err)))
+				continue
+			}
+			parent.set(item)
+			if err := h.validate(c.UserContext(), c, v, item); err != nil {
+				b.fail(i, h.problem(c, err))
+				continue
+			}
+			items[i] = item
+		}
+
+		err = h.writeBulk(c, b, func(ctx context.Context, indexes []int) error {
+			batch := make([]*T, len(indexes))
+			for j, i := range indexes {
+				batch[j] = items[i]
+			}
+			_, err := h.service.CreateInBatchesContext(ctx, batch, BulkBatchSize)
+			return err
+		}, http.StatusCreated)
+		if err != nil {
+			return h.sendError(c, err)
+		}
+		for i, item := range items {
+			if b.items[i].Status == http.StatusCreated {
+				b.items[i].ID = reflect.ValueOf(item).Elem().FieldByName("ID").Interface()
+			}
+		}
+		return b.send(c)
+	}
+}
+
+// FxBulkUpdate applies the partial updates of a JSON array body, see
+// BulkMode. Each item holds the id of the item to update and the fields to
+// change, which are validated and written like plain JSON PATCH bodies. The
+// items are loaded within the transaction writing them.
+func (h *Handler[T]) FxBulkUpdate(vals ...*validator.StructValidator) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		mode, err := parseBulkMode(c)
+		if err != nil {
+			return h.sendError(c, badInput(err))
+		}
+		parent, err := h.parentOf(c)
+		if err != nil {
+			return h.sendError(c, err)
+		}
+		raws, err := decodeBulk(c.Body())
+		if err != nil {
+			return h.sendError(c, badInput(err))
+		}
+		var v *validator.StructValidator
+		if len(vals) > 0 {
+			v = vals[0]
+		}
+
+		b := newBulk(mode, len(raws))
+		bodies := make([]map[string]json.RawMessage, len(raws))
+		for i, raw := range raws {
+			body := map[string]json.RawMessage{}
+			if err := json.Unmarshal(raw, &body); err != nil {
+				b.fail(i, h.problem(c, badInput(err)))
+				continue
+			}
+			var id int64
+			if err := json.Unmarshal(body["id"], &id); err != nil || id == 0 {
+				b.fail(i, h.problem(c, badInput(errors.New("each item needs the numeric id of the item to update"))))
+				continue
+			}
+			b.items[i].ID = id
+			bodies[i] = body
+		}
+
+		// The items are loaded, validated and written in the transaction
+		// of the batch, so they cannot change in between
+		update := func(ctx context.Context, i int) error {
+			item, err := h.service.GetByIDWithQueryContext(ctx, b.items[i].ID, parent.query())
+			if err != nil {
+				return err
+			}
+			if err := json.Unmarshal(raws[i], item); err != nil {
+				return badInput(err)
+			}
+			parent.set(item)
+			present, columns, err := h.patchColumns(bodies[i])
+			if err != nil {
+				return err
+			}
+			if err := h.validatePartial(ctx, c, v, item, present); err != nil {
+				return err
+			}
+			_, err = h.service.UpdateColumnsContext(ctx, item, columns)
+			return err
+		}
+
+		err = h.writeBulk(c, b, func(ctx context.Context, indexes []int) error {
+			for _, i := range indexes {
+				if err := update(ctx, i); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, http.StatusOK)
+		if err != nil {
+			return h.sendError(c, err)
+		}
+		return b.send(c)
+	}
+}
+
+// DeleteByIDs deletes the items listed in ?ids=1,2,3, see BulkMode. IDs not
+// found are reported per item, and IDs listed twice once.
+func (h *Handler[T]) DeleteByIDs(c *fiber.Ctx) error {
+	mode, err := parseBulkMode(c)
+	if err != nil {
+		return h.sendError(c, badInput(err))
+	}
+	parent, err := h.parentOf(c)
+	if err != nil {
+		return h.sendError(c, err)
+	}
+	raw := c.Query("ids")
+	if raw == "" {
+		return h.sendError(c, badInput(errors.New("ids is required")))
+	}
+	parts := strings.Split(raw, ",")
+	if len(parts) > MaxBulkItems {
+		return h.sendError(c, badInput(fmt.Errorf("at most %d items are allowed per request", MaxBulkItems)))
+	}
+	// Each ID is reported once, in the order it first appears
+	ids := []interface{}{}
+	seen := map[int64]bool{}
+	for _, part := range parts {
+		id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+		if err != nil {
+			return h.sendError(c, badInput(fmt.Errorf("invalid id %q", part)))
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	// The IDs missing, or not children of the parent, are not deleted by the
+	// DELETE statement, which checks the parent itself. A batch deleting
+	// less items than asked is retried item by item to report them.
+	query := parent.query()
+	b := newBulk(mode, len(ids))
+	for i, id := range ids {
+		b.items[i].ID = id
+	}
+	err = h.writeBulk(c, b, func(ctx context.Context, indexes []int) error {
+		batch := make([]interface{}, len(indexes))
+		for j, i := range indexes {
+			batch[j] = ids[i]
+		}
+		deleted, err := h.service.DeleteByIDsWithQueryContext(ctx, batch, query)
+		if err != nil {
+			return err
+		}
+		if deleted < int64(len(batch)) {
+			return repositories.ErrNotFound
+		}
+		return nil
+	}, http.StatusOK)
+	if err != nil {
+		return h.sendError(c, err)
+	}
+	return b.send(c)
+}
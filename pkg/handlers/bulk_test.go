@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/model"
+	"github.com/arturoeanton/go-struc2fiber/pkg/validator"
+	fiber "github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// bulkStatuses returns the status of each item of a bulk response body
+func bulkStatuses(t *testing.T, body string) []int {
+	t.Helper()
+	var result BulkResult
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		t.Fatalf("%v: %s", err, body)
+	}
+	statuses := []int{}
+	for _, item := range result.Items {
+		statuses = append(statuses, item.Status)
+	}
+	return statuses
+}
+
+// skillValues returns the value of each stored skill by name
+func skillValues(t *testing.T, db *gorm.DB) map[string]int {
+	t.Helper()
+	var rows []model.Skill
+	if err := db.Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	result := map[string]int{}
+	for _, row := range rows {
+		result[row.Name] = row.Value
+	}
+	return result
+}
+
+func TestBulk(t *testing.T) {
+	v := validator.NewStructValidator()
+	err := v.LoadSchemaFromYAML(`
+name: Skill
+rules:
+  - field: value
+    type: number
+    max: 100
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Skills go and c exist with values 30 and 20
+	stored := map[string]int{"go": 30, "c": 20}
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		body     string
+		status   int
+		statuses []int
+		want     map[string]int
+	}{
+		{"create", "POST", "/skill/_bulk", `[{"name":"zig","value":1},{"name":"rust","value":2}]`,
+			fiber.StatusOK, []int{201, 201}, map[string]int{"go": 30, "c": 20, "zig": 1, "rust": 2}},
+		{"create invalid", "POST", "/skill/_bulk", `[{"name":"zig","value":1},{"name":"rust","value":200}]`,
+			fiber.StatusUnprocessableEntity, []int{424, 422}, stored},
+		// The duplicate fails on insert, after zig was written then rolled back
+		{"create duplicate", "POST", "/skill/_bulk", `[{"name":"zig","value":1},{"name":"go","value":2},{"name":"rust","value":3}]`,
+			fiber.StatusConflict, []int{424, 409, 424}, stored},
+		{"create best effort", "POST", "/skill/_bulk?mode=best_effort", `[{"name":"zig","value":1},{"name":"go","value":2},{"name":"rust","value":300}]`,
+			fiber.StatusMultiStatus, []int{201, 409, 422}, map[string]int{"go": 30, "c": 20, "zig": 1}},
+		{"update", "PATCH", "/skill/_bulk", `[{"id":1,"value":31},{"id":2,"value":21}]`,
+			fiber.StatusOK, []int{200, 200}, map[string]int{"go": 31, "c": 21}},
+		// Skill 1 is updated then rolled back when skill 9 is not found
+		{"update missing", "PATCH", "/skill/_bulk", `[{"id":1,"value":31},{"id":9,"value":1}]`,
+			fiber.StatusNotFound, []int{424, 404}, stored},
+		{"update invalid", "PATCH", "/skill/_bulk", `[{"id":1,"value":31},{"id":2,"value":210}]`,
+			fiber.StatusUnprocessableEntity, []int{424, 422}, stored},
+		{"update best effort", "PATCH", "/skill/_bulk?mode=best_effort", `[{"id":1,"value":31},{"id":9,"value":1}]`,
+			fiber.StatusMultiStatus, []int{200, 404}, map[string]int{"go": 31, "c": 20}},
+		{"delete missing", "DELETE", "/skill?ids=1,9", "",
+			fiber.StatusNotFound, []int{424, 404}, stored},
+		{"delete best effort", "DELETE", "/skill?ids=1,9&mode=best_effort", "",
+			fiber.StatusMultiStatus, []int{200, 404}, map[string]int{"c": 20}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t,
+				&model.Skill{UserID: 1, Name: "go", Value: 30},
+				&model.Skill{UserID: 1, Name: "c", Value: 20},
+			)
+			if err := db.Exec("CREATE UNIQUE INDEX idx_skill_name ON skill(name)").Error; err != nil {
+				t.Fatal(err)
+			}
+			h := newSkillHandler()
+			app := fiber.New()
+			app.Post("/skill/_bulk", h.FxBulkCreate(v))
+			app.Patch("/skill/_bulk", h.FxBulkUpdate(v))
+			app.Delete("/skill", h.DeleteByIDs)
+
+			resp, body := send(t, app, tt.method, tt.target, tt.body)
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.status, body)
+			}
+			if got := bulkStatuses(t, body); !reflect.DeepEqual(got, tt.statuses) {
+				t.Errorf("item statuses = %v, want %v: %s", got, tt.statuses, body)
+			}
+			if got := skillValues(t, db); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("skills = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBulkBatches(t *testing.T) {
+	defer func(size int) { BulkBatchSize = size }(BulkBatchSize)
+	BulkBatchSize = 2
+
+	db := openTestDB(t, &model.Skill{UserID: 1, Name: "go", Value: 30})
+	if err := db.Exec("CREATE UNIQUE INDEX idx_skill_name ON skill(name)").Error; err != nil {
+		t.Fatal(err)
+	}
+	app := fiber.New()
+	app.Post("/skill/_bulk", newSkillHandler().FxBulkCreate())
+
+	// The first batch is written before the second one fails, then rolled back
+	body := `[{"name":"a"},{"name":"b"},{"name":"c"},{"name":"go"},{"name":"d"}]`
+	resp, data := send(t, app, "POST", "/skill/_bulk", body)
+	if resp.StatusCode != fiber.StatusConflict {
+		t.Fatalf("atomic: status = %d, want 409: %s", resp.StatusCode, data)
+	}
+	if got, want := bulkStatuses(t, data), []int{424, 424, 424, 409, 424}; !reflect.DeepEqual(got, want) {
+		t.Errorf("atomic: item statuses = %v, want %v", got, want)
+	}
+	if got, want := skillValues(t, db), map[string]int{"go": 30}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("atomic: skills = %v, want %v", got, want)
+	}
+
+	resp, data = send(t, app, "POST", "/skill/_bulk?mode=best_effort", body)
+	if resp.StatusCode != fiber.StatusMultiStatus {
+		t.Fatalf("best effort: status = %d, want 207: %s", resp.StatusCode, data)
+	}
+	if got, want := bulkStatuses(t, data), []int{201, 201, 201, 409, 201}; !reflect.DeepEqual(got, want) {
+		t.Errorf("best effort: item statuses = %v, want %v", got, want)
+	}
+	if got, want := skillValues(t, db), map[string]int{"go": 30, "a": 0, "b": 0, "c": 0, "d": 0}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("best effort: skills = %v, want %v", got, want)
+	}
+}
+
+func TestBulkUpdateLoadsWithinTransaction(t *testing.T) {
+	db := openTestDB(t, skills(2)...)
+	app := fiber.New()
+	app.Patch("/skill/_bulk", newSkillHandler().FxBulkUpdate())
+
+	// Record whether each query ran in a transaction
+	inTx := []bool{}
+	if err := db.Callback().Query().Before("gorm:query").Register("test:tx", func(tx *gorm.DB) {
+		_, ok := tx.Statement.ConnPool.(gorm.TxCommitter)
+		inTx = append(inTx, ok)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, body := send(t, app, "PATCH", "/skill/_bulk", `[{"id":1,"value":1},{"id":2,"value":2}]`)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d: %s", resp.StatusCode, body)
+	}
+	if want := []bool{true, true}; !reflect.DeepEqual(inTx, want) {
+		t.Fatalf("queries in a transaction = %v, want %v", inTx, want)
+	}
+}
+
+func TestNestedBulkDelete(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   string
+		status   int
+		statuses []int
+		want     map[string]int
+	}{
+		// Skill 3 belongs to bob
+		{"other parent", "/internal_users/1/skills?ids=1,3", fiber.StatusNotFound, []int{424, 404},
+			map[string]int{"go": 30, "c": 20, "zig": 10}},
+		{"other parent best effort", "/internal_users/1/skills?ids=1,3&mode=best_effort", fiber.StatusMultiStatus, []int{200, 404},
+			map[string]int{"c": 20, "zig": 10}},
+		{"duplicates", "/internal_users/1/skills?ids=2,1,2", fiber.StatusOK, []int{200, 200},
+			map[string]int{"zig": 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t,
+				&model.InternalUser{Username: "ana", Email: "ana@example.com"},
+				&model.InternalUser{Username: "bob", Email: "bob@example.com"},
+				&model.Skill{UserID: 1, Name: "go", Value: 30},
+				&model.Skill{UserID: 1, Name: "c", Value: 20},
+				&model.Skill{UserID: 2, Name: "zig", Value: 10},
+			)
+			app := fiber.New()
+			app.Delete("/internal_users/:user_id/skills", newNestedSkillHandler(t).DeleteByIDs)
+
+			resp, body := send(t, app, "DELETE", tt.target, "")
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.status, body)
+			}
+			if got := bulkStatuses(t, body); !reflect.DeepEqual(got, tt.statuses) {
+				t.Errorf("item statuses = %v, want %v: %s", got, tt.statuses, body)
+			}
+			if got := skillValues(t, db); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("skills = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNestedBulkDeleteStatement(t *testing.T) {
+	db := openTestDB(t,
+		&model.InternalUser{Username: "ana", Email: "ana@example.com"},
+		&model.Skill{UserID: 1, Name: "go", Value: 30},
+	)
+	app := fiber.New()
+	app.Delete("/internal_users/:user_id/skills", newNestedSkillHandler(t).DeleteByIDs)
+
+	// Record the statements run to check the parent is checked by the DELETE
+	statements := []string{}
+	record := func(tx *gorm.DB) { statements = append(statements, tx.Statement.SQL.String()) }
+	if err := db.Callback().Delete().After("gorm:delete").Register("test:statements", record); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Callback().Query().After("gorm:query").Register("test:statements", record); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, body := send(t, app, "DELETE", "/internal_users/1/skills?ids=1", "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d: %s", resp.StatusCode, body)
+	}
+	// The parent is looked up, then the skills deleted with one statement
+	if len(statements) != 2 || !strings.HasPrefix(statements[1], "DELETE") || !strings.Contains(statements[1], "`user_id` = ?") {
+		t.Fatalf("statements = %q, want one DELETE restricted to the parent", statements)
+	}
+}
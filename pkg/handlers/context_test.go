@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/model"
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+func TestCanceledRequestContext(t *testing.T) {
+	db := openTestDB(t, &model.Skill{UserID: 1, Name: "go", Value: 30})
+	h := newSkillHandler()
+	app := fiber.New()
+	// The queries of the handlers run with the context of the request
+	app.Use(func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithCancel(c.UserContext())
+		cancel()
+		c.SetUserContext(ctx)
+		return c.Next()
+	})
+	app.Get("/skill", h.GetAll)
+	app.Get("/skill/:id", h.GetByID)
+	app.Post("/skill", h.FxCreate())
+	app.Put("/skill/:id", h.FxUpdate())
+	app.Patch("/skill/:id", h.FxPatch())
+	app.Delete("/skill/:id", h.DeleteByID)
+	app.Post("/skill/_bulk", h.FxBulkCreate())
+
+	tests := []struct {
+		method string
+		target string
+		body   string
+	}{
+		{"GET", "/skill", ""},
+		{"GET", "/skill/1", ""},
+		{"POST", "/skill", `{"user_id":1,"name":"c","value":1}`},
+		{"PUT", "/skill/1", `{"user_id":1,"name":"c","value":1}`},
+		{"PATCH", "/skill/1", `{"name":"c"}`},
+		{"DELETE", "/skill/1", ""},
+		{"POST", "/skill/_bulk", `[{"user_id":1,"name":"c","value":1}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			resp, body := send(t, app, tt.method, tt.target, tt.body)
+			if resp.StatusCode != fiber.StatusInternalServerError {
+				t.Fatalf("status = %d, want 500: %s", resp.StatusCode, body)
+			}
+		})
+	}
+
+	var stored []model.Skill
+	if err := db.Find(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 || stored[0].Name != "go" || stored[0].Value != 30 {
+		t.Fatalf("skills = %+v, want skill go unchanged", stored)
+	}
+}
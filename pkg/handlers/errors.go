@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/repositories"
+	"github.com/arturoeanton/go-struc2fiber/pkg/validator"
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+// MIMEProblemJSON is the content type of RFC 7807 responses
+const MIMEProblemJSON = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object
+type Problem struct {
+	Type     string         `json:"type"`
+	Title    string         `json:"title"`
+	Status   int            `json:"status"`
+	Detail   string         `json:"detail,omitempty"`
+	Instance string         `json:"instance,omitempty"`
+	Code     string         `json:"code,omitempty"`
+	Errors   []ProblemField `json:"errors,omitempty"`
+}
+
+// ProblemField describes why a single field was rejected
+type ProblemField struct {
+	Field   string                 `json:"field,omitempty"`
+	Rule    string                 `json:"rule,omitempty"`
+	Code    string                 `json:"code,omitempty"`
+	Params  map[string]interface{} `json:"params,omitempty"`
+	Message string                 `json:"message"`
+}
+
+var (
+	// ErrorRenderer writes every error response of the handlers, replace it
+	// to change the error format of the whole API
+	ErrorRenderer func(c *fiber.Ctx, p *Problem) error = RenderProblem
+	// ProblemTypeBase is prefixed to the error code to build the problem type
+	// URI, problems are typed "about:blank" when empty
+	ProblemTypeBase = ""
+)
+
+// statusByKind maps error kinds to HTTP status codes
+var statusByKind = map[repositories.ErrorKind]int{
+	repositories.KindInternal:   http.StatusInternalServerError,
+	repositories.KindNotFound:   http.StatusNotFound,
+	repositories.KindConflict:   http.StatusConflict,
+	repositories.KindValidation: http.StatusUnprocessableEntity,
+	repositories.KindConstraint: http.StatusUnprocessableEntity,
+	repositories.KindBadInput:   http.StatusBadRequest,
+}
+
+// validationFailed wraps the errors of a failed validation
+func validationFailed(errs validator.ValidationErrors) error {
+	return &repositories.Error{Kind: repositories.KindValidation, Message: "Validation failed", Err: errs}
+}
+
+// badInput classifies err as a malformed request
+func badInput(err error) error {
+	return repositories.NewError(repositories.KindBadInput, err)
+}
+
+// NewProblem builds the problem describing a response with the given status
+func NewProblem(c *fiber.Ctx, status int, code string, detail string) *Problem {
+	p := &Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: c.OriginalURL(),
+		Code:     code,
+	}
+	if ProblemTypeBase != "" && code != "" {
+		p.Type = ProblemTypeBase + code
+	}
+	return p
+}
+
+// RenderProblem writes p as application/problem+json
+func RenderProblem(c *fiber.Ctx, p *Problem) error {
+	return c.Status(p.Status).JSON(p, MIMEProblemJSON)
+}
+
+// ProblemErrorHandler reports the errors returned to Fiber, like unknown
+// routes, as problems. Use it as fiber.Config.ErrorHandler.
+func ProblemErrorHandler(c *fiber.Ctx, err error) error {
+	status := http.StatusInternalServerError
+	detail := "Internal Server Error"
+	var fe *fiber.Error
+	if errors.As(err, &fe) {
+		status = fe.Code
+		detail = fe.Message
+	}
+	return ErrorRenderer(c, NewProblem(c, status, "", detail))
+}
+
+// sendError writes err with the status code matching its kind. Internal
+// errors are reported without details.
+func (h *Handler[T]) sendError(c *fiber.Ctx, err error) error {
+	return ErrorRenderer(c, h.problem(c, err))
+}
+
+// problem describes err, see sendError
+func (h *Handler[T]) problem(c *fiber.Ctx, err error) *Problem {
+	kind := repositories.KindOf(err)
+	p := NewProblem(c, statusByKind[kind], kind.String(), err.Error())
+	switch kind {
+	case repositories.KindInternal:
+		p.Detail = "Failed to process " + h.Name()
+	case repositories.KindNotFound:
+		// Keep the details naming another resource, like a missing parent
+		if err.Error() == repositories.ErrNotFound.Message {
+			p.Detail = h.Name() + " not found"
+		}
+	}
+	var fields validator.ValidationErrors
+	if errors.As(err, &fields) {
+		for _, field := range fields {
+			p.Errors = append(p.Errors, ProblemField{
+				Field:   field.Field,
+				Rule:    field.Rule,
+				Code:    field.Code,
+				Params:  field.Params,
+				Message: field.Message,
+			})
+		}
+	}
+	return p
+}
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/model"
+	"github.com/arturoeanton/go-struc2fiber/pkg/repositories"
+	"github.com/arturoeanton/go-struc2fiber/pkg/validator"
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+func TestErrorStatus(t *testing.T) {
+	db := openTestDB(t, &model.Skill{UserID: 1, Name: "go", Value: 30})
+	if err := db.Exec("CREATE UNIQUE INDEX idx_skill_name ON skill(name)").Error; err != nil {
+		t.Fatal(err)
+	}
+	h := newSkillHandler()
+	app := fiber.New()
+	app.Get("/skill/:id", h.GetByID)
+	app.Post("/skill", h.FxCreate())
+	app.Put("/skill/:id", h.FxUpdate())
+	app.Delete("/skill/:id", h.DeleteByID)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		want   int
+	}{
+		{"get missing", "GET", "/skill/99", "", fiber.StatusNotFound},
+		{"update missing", "PUT", "/skill/99", `{"name":"c"}`, fiber.StatusNotFound},
+		{"update invalid id", "PUT", "/skill/abc", `{"name":"c"}`, fiber.StatusBadRequest},
+		{"delete missing", "DELETE", "/skill/99", "", fiber.StatusNotFound},
+		{"create duplicate", "POST", "/skill", `{"user_id":1,"name":"go","value":1}`, fiber.StatusConflict},
+		{"create malformed", "POST", "/skill", `{"name":`, fiber.StatusBadRequest},
+		{"get", "GET", "/skill/1", "", fiber.StatusOK},
+		{"delete", "DELETE", "/skill/1", "", fiber.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := send(t, app, tt.method, tt.target, tt.body)
+			if resp.StatusCode != tt.want {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.want, body)
+			}
+		})
+	}
+}
+
+func TestErrorStatusByKind(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{repositories.ErrNotFound, fiber.StatusNotFound},
+		{repositories.ErrConflict, fiber.StatusConflict},
+		{repositories.ErrValidation, fiber.StatusUnprocessableEntity},
+		{repositories.ErrConstraint, fiber.StatusUnprocessableEntity},
+		{repositories.ErrBadInput, fiber.StatusBadRequest},
+		{badInput(errors.New("bad")), fiber.StatusBadRequest},
+		{errors.New("disk full"), fiber.StatusInternalServerError},
+	}
+	h := newSkillHandler()
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/", func(c *fiber.Ctx) error {
+				return h.sendError(c, tt.err)
+			})
+			resp, body := send(t, app, "GET", "/", "")
+			if resp.StatusCode != tt.want {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.want, body)
+			}
+		})
+	}
+}
+
+func TestProblemRendering(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Problem
+	}{
+		{"not found", repositories.ErrNotFound, Problem{
+			Type: "about:blank", Title: "Not Found", Status: 404, Detail: "skill not found", Instance: "/skill/7?x=1", Code: "not_found",
+		}},
+		{"parent not found", &repositories.Error{Kind: repositories.KindNotFound, Message: "internal_users not found"}, Problem{
+			Type: "about:blank", Title: "Not Found", Status: 404, Detail: "internal_users not found", Instance: "/skill/7?x=1", Code: "not_found",
+		}},
+		{"bad input", badInput(errors.New("invalid page")), Problem{
+			Type: "about:blank", Title: "Bad Request", Status: 400, Detail: "invalid page", Instance: "/skill/7?x=1", Code: "bad_input",
+		}},
+		// Internal errors are reported without their details
+		{"internal", errors.New("dial tcp 10.0.0.1: refused"), Problem{
+			Type: "about:blank", Title: "Internal Server Error", Status: 500, Detail: "Failed to process skill", Instance: "/skill/7?x=1", Code: "internal",
+		}},
+	}
+	h := newSkillHandler()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/skill/:id", func(c *fiber.Ctx) error {
+				return h.sendError(c, tt.err)
+			})
+			resp, body := send(t, app, "GET", "/skill/7?x=1", "")
+			if got := resp.Header.Get(fiber.HeaderContentType); got != MIMEProblemJSON {
+				t.Errorf("Content-Type = %q, want %q", got, MIMEProblemJSON)
+			}
+			var got Problem
+			if err := json.Unmarshal([]byte(body), &got); err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.want.Status || !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %d %+v, want %+v", resp.StatusCode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProblemTypeAndRenderer(t *testing.T) {
+	h := newSkillHandler()
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		return h.sendError(c, repositories.ErrConflict)
+	})
+
+	defer func(base string) { ProblemTypeBase = base }(ProblemTypeBase)
+	ProblemTypeBase = "https://example.com/problems/"
+	_, body := send(t, app, "GET", "/", "")
+	var p Problem
+	if err := json.Unmarshal([]byte(body), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Type != "https://example.com/problems/conflict" {
+		t.Errorf("type = %q, want https://example.com/problems/conflict", p.Type)
+	}
+
+	defer func(renderer func(c *fiber.Ctx, p *Problem) error) { ErrorRenderer = renderer }(ErrorRenderer)
+	ErrorRenderer = func(c *fiber.Ctx, p *Problem) error {
+		return c.Status(p.Status).JSON(fiber.Map{"error": p.Code})
+	}
+	resp, body := send(t, app, "GET", "/", "")
+	if resp.StatusCode != fiber.StatusConflict || body != `{"error":"conflict"}` {
+		t.Errorf("custom renderer: got %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestProblemErrorHandler(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: ProblemErrorHandler})
+	app.Get("/boom", func(c *fiber.Ctx) error {
+		return errors.New("boom")
+	})
+
+	tests := []struct {
+		target string
+		want   Problem
+	}{
+		{"/missing", Problem{Type: "about:blank", Title: "Not Found", Status: 404, Detail: "Cannot GET /missing", Instance: "/missing"}},
+		{"/boom", Problem{Type: "about:blank", Title: "Internal Server Error", Status: 500, Detail: "Internal Server Error", Instance: "/boom"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			resp, body := send(t, app, "GET", tt.target, "")
+			if got := resp.Header.Get(fiber.HeaderContentType); got != MIMEProblemJSON {
+				t.Errorf("Content-Type = %q, want %q", got, MIMEProblemJSON)
+			}
+			var got Problem
+			if err := json.Unmarshal([]byte(body), &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidationProblem(t *testing.T) {
+	openTestDB(t)
+	v := validator.NewStructValidator()
+	err := v.LoadSchemaFromYAML(`
+name: Skill
+rules:
+  - field: name
+    type: string
+    required: true
+    minLength: 3
+  - field: value
+    type: number
+    max: 100
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := fiber.New()
+	app.Post("/skill", newSkillHandler().FxCreate(v))
+
+	resp, body := send(t, app, "POST", "/skill", `{"name":"go","value":150}`)
+	if resp.StatusCode != fiber.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422: %s", resp.StatusCode, body)
+	}
+	var p Problem
+	if err := json.Unmarshal([]byte(body), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Code != "validation" || p.Detail != "Validation failed" {
+		t.Errorf("problem = %+v", p)
+	}
+	sort.Slice(p.Errors, func(i, j int) bool { return p.Errors[i].Field < p.Errors[j].Field })
+	want := []ProblemField{
+		{Field: "name", Rule: "minLength", Code: "min_length", Params: map[string]interface{}{"min": 3.0}, Message: "Field 'name' must have at least 3 characters"},
+		{Field: "value", Rule: "max", Code: "max", Params: map[string]interface{}{"max": 100.0}, Message: "Field 'value' must be <= 100"},
+	}
+	if !reflect.DeepEqual(p.Errors, want) {
+		t.Fatalf("errors = %#v, want %#v", p.Errors, want)
+	}
+}
+
+func TestValidationDatabaseFailure(t *testing.T) {
+	db := openTestDB(t, &model.InternalUser{Username: "ana", Email: "ana@example.com"})
+	v := validator.NewStructValidator()
+	err := v.LoadSchemaFromYAML(`
+name: Skill
+rules:
+  - field: user_id
+    type: integer
+    exists: {table: internal_user}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := fiber.New()
+	app.Post("/skill", newSkillHandler().FxCreate(v))
+
+	resp, body := send(t, app, "POST", "/skill", `{"user_id":9,"name":"go"}`)
+	if resp.StatusCode != fiber.StatusUnprocessableEntity {
+		t.Fatalf("missing user: status = %d, want 422: %s", resp.StatusCode, body)
+	}
+
+	if err := db.Exec("DROP TABLE internal_user").Error; err != nil {
+		t.Fatal(err)
+	}
+	resp, body = send(t, app, "POST", "/skill", `{"user_id":1,"name":"go"}`)
+	if resp.StatusCode != fiber.StatusInternalServerError {
+		t.Fatalf("failed check: status = %d, want 500: %s", resp.StatusCode, body)
+	}
+	var count int64
+	if err := db.Table("skill").Count(&count).Error; err != nil || count != 0 {
+		t.Fatalf("skills = %d, %v, want none written", count, err)
+	}
+}
+
+func TestCreateWithoutOptionalReference(t *testing.T) {
+	db := openTestDB(t, &model.InternalUser{Username: "ana", Email: "ana@example.com"})
+	v := validator.NewStructValidator()
+	if err := v.LoadSchemaFromFile("../../schemas/generated/skill.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	app := fiber.New()
+	app.Post("/skill", newSkillHandler().FxCreate(v))
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		// user_id is optional, its exists check only runs when it is sent
+		{"without user", `{"name":"golang","value":30}`, fiber.StatusOK},
+		{"existing user", `{"user_id":1,"name":"rust","value":30}`, fiber.StatusOK},
+		{"missing user", `{"user_id":9,"name":"zig","value":30}`, fiber.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := send(t, app, "POST", "/skill", tt.body)
+			if resp.StatusCode != tt.want {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.want, body)
+			}
+		})
+	}
+	var count int64
+	if err := db.Table("skill").Count(&count).Error; err != nil || count != 2 {
+		t.Fatalf("skills = %d, %v, want 2", count, err)
+	}
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/repositories"
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+// parseFields reads ?fields=id,username,email from the request and returns
+// the columns to select along with the JSON names to keep in the response
+func (h *Handler[T]) parseFields(c *fiber.Ctx) ([]string, map[string]bool, error) {
+	raw := c.Query("fields")
+	if raw == "" {
+		return nil, nil, nil
+	}
+	fields, err := repositories.Fields[T]()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	columns := []string{}
+	keep := map[string]bool{}
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		field, ok := fields[name]
+		if !ok || field.Column == "" {
+			return nil, nil, fmt.Errorf("unknown field %s", name)
+		}
+		columns = append(columns, field.Column)
+		keep[name] = true
+	}
+	return columns, keep, nil
+}
+
+// trimFields returns the JSON objects of data, an item or a list of items,
+// holding only the keys in keep. data is returned as is when keep is nil.
+func trimFields(data interface{}, keep map[string]bool) (interface{}, error) {
+	if keep == nil {
+		return data, nil
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(string(raw)), "[") {
+		items := []map[string]json.RawMessage{}
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			trimObject(item, keep)
+		}
+		return items, nil
+	}
+
+	item := map[string]json.RawMessage{}
+	if err := json.Unmarshal(raw, &item); err != nil {
+		return nil, err
+	}
+	trimObject(item, keep)
+	return item, nil
+}
+
+func trimObject(item map[string]json.RawMessage, keep map[string]bool) {
+	for key := range item {
+		if !keep[key] {
+			delete(item, key)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/model"
+	fiber "github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// fieldsOf runs parseFields on a request with the given query string
+func fieldsOf(t *testing.T, h *Handler[model.Skill], query string) ([]string, map[string]bool, error) {
+	t.Helper()
+	var columns []string
+	var keep map[string]bool
+	var err error
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		columns, keep, err = h.parseFields(c)
+		return nil
+	})
+	if _, testErr := app.Test(httptest.NewRequest("GET", "/?"+query, nil)); testErr != nil {
+		t.Fatal(testErr)
+	}
+	return columns, keep, err
+}
+
+func TestParseFields(t *testing.T) {
+	h := &Handler[model.Skill]{name: "skill"}
+
+	tests := []struct {
+		query   string
+		columns []string
+		keep    map[string]bool
+	}{
+		{"", nil, nil},
+		{"fields=name", []string{"name"}, map[string]bool{"name": true}},
+		{"fields=id,name,%20value", []string{"id", "name", "value"}, map[string]bool{"id": true, "name": true, "value": true}},
+		{"fields=user_id", []string{"user_id"}, map[string]bool{"user_id": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			columns, keep, err := fieldsOf(t, h, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(columns, tt.columns) || !reflect.DeepEqual(keep, tt.keep) {
+				t.Fatalf("got %v %v, want %v %v", columns, keep, tt.columns, tt.keep)
+			}
+		})
+	}
+
+	for _, query := range []string{"fields=unknown", "fields=name,", "fields=Name"} {
+		if _, _, err := fieldsOf(t, h, query); err == nil || !strings.Contains(err.Error(), "unknown field") {
+			t.Errorf("%s: err = %v, want unknown field", query, err)
+		}
+	}
+}
+
+func TestTrimFields(t *testing.T) {
+	skill := &model.Skill{ID: 1, UserID: 2, Name: "go", Value: 30}
+	keep := map[string]bool{"id": true, "name": true}
+
+	tests := []struct {
+		name string
+		data interface{}
+		keep map[string]bool
+		want string
+	}{
+		{"no fields", skill, nil, `{"id":1,"user_id":2,"name":"go","value":30}`},
+		{"item", skill, keep, `{"id":1,"name":"go"}`},
+		{"list", []*model.Skill{skill, {ID: 2, Name: "c"}}, keep, `[{"id":1,"name":"go"},{"id":2,"name":"c"}]`},
+		{"empty list", []*model.Skill{}, keep, `[]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := trimFields(tt.data, tt.keep)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := json.Marshal(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Fatalf("got %s, want %s", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadSparseFieldsets(t *testing.T) {
+	db := openTestDB(t,
+		&model.InternalUser{Username: "ana", Email: "ana@example.com"},
+		&model.Skill{UserID: 1, Name: "go", Value: 30},
+		&model.Skill{UserID: 1, Name: "c", Value: 20},
+	)
+	h := newSkillHandler()
+	app := fiber.New()
+	app.Get("/skill", h.GetAll)
+	app.Get("/skill/:id", h.GetByID)
+
+	// Record the statements run to check only the columns asked are selected
+	statements := []string{}
+	if err := db.Callback().Query().After("gorm:query").Register("test:statements", func(tx *gorm.DB) {
+		statements = append(statements, tx.Statement.SQL.String())
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		target string
+		want   string
+		sql    string
+	}{
+		{"/skill?fields=name", `[{"name":"go"},{"name":"c"}]`, "SELECT id,`name` FROM `skill`"},
+		{"/skill?fields=id,value&sort=&page=1", `[{"id":1,"value":30},{"id":2,"value":20}]`, "SELECT `id`,`value` FROM `skill`"},
+		{"/skill/2?fields=name,value", `{"name":"c","value":20}`, "SELECT id,`name`,`value` FROM `skill`"},
+		{"/skill/1", `{"id":1,"user_id":1,"name":"go","value":30}`, "SELECT * FROM `skill`"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			statements = statements[:0]
+			resp, body := send(t, app, "GET", tt.target, "")
+			if resp.StatusCode != fiber.StatusOK {
+				t.Fatalf("status = %d: %s", resp.StatusCode, body)
+			}
+			if body != tt.want {
+				t.Errorf("body = %s, want %s", body, tt.want)
+			}
+			found := false
+			for _, sql := range statements {
+				found = found || strings.HasPrefix(sql, tt.sql)
+			}
+			if !found {
+				t.Errorf("statements = %q, want one starting with %q", statements, tt.sql)
+			}
+		})
+	}
+
+	resp, body := send(t, app, "GET", "/skill/1?fields=password", "")
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("unknown field: status = %d, want 400: %s", resp.StatusCode, body)
+	}
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/repositories"
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+// reservedParams are query string keys never read as filters
+var reservedParams = map[string]bool{
+	"page":     true,
+	"per_page": true,
+	"limit":    true,
+	"offset":   true,
+	"cursor":   true,
+	"sort":     true,
+	"fields":   true,
+	"include":  true,
+}
+
+// filterKey matches keys like "name" or "value[gte]"
+var filterKey = regexp.MustCompile(`^([^\[\]]+)(?:\[([a-z_]+)\])?$`)
+
+// SetFilterable sets the fields, by JSON name, clients can filter listings on
+func (h *Handler[T]) SetFilterable(jsonNames ...string) error {
+	filterable, err := resolveFields[T](h.Name(), jsonNames)
+	if err != nil {
+		return err
+	}
+	h.filterable = filterable
+	return nil
+}
+
+// parseFilters reads filters like ?value[gte]=10&name[like]=go% from the
+// request. A key without operator compares for equality. Query string keys
+// that are not fields of the model are ignored.
+func (h *Handler[T]) parseFilters(c *fiber.Ctx) ([]repositories.Filter, error) {
+	fields, err := repositories.Fields[T]()
+	if err != nil {
+		return nil, err
+	}
+
+	filters := []repositories.Filter{}
+	c.Context().QueryArgs().VisitAll(func(key, value []byte) {
+		if err != nil {
+			return
+		}
+		match := filterKey.FindStringSubmatch(string(key))
+		if match == nil || reservedParams[match[1]] {
+			return
+		}
+		name, op := match[1], repositories.Operator(match[2])
+		if _, isField := fields[name]; !isField && op == "" {
+			return
+		}
+		field, ok := h.filterable[name]
+		if !ok {
+			err = fmt.Errorf("field %s is not filterable", name)
+			return
+		}
+		if op == "" {
+			op = repositories.Eq
+		}
+		var filter repositories.Filter
+		filter, err = newFilter(field, op, string(value))
+		filters = append(filters, filter)
+	})
+	return filters, err
+}
+
+// newFilter converts a query string value to the type of the field
+func newFilter(field *repositories.Field, op repositories.Operator, raw string) (repositories.Filter, error) {
+	filter := repositories.Filter{Column: field.Column, Operator: op}
+	switch op {
+	case repositories.Eq, repositories.Ne, repositories.Lt, repositories.Lte, repositories.Gt, repositories.Gte:
+		value, err := repositories.ParseValue(field.Type, raw)
+		if err != nil {
+			return filter, fmt.Errorf("invalid value %q for %s", raw, field.JSONName)
+		}
+		filter.Value = value
+	case repositories.In:
+		values := []interface{}{}
+		for _, part := range strings.Split(raw, ",") {
+			value, err := repositories.ParseValue(field.Type, part)
+			if err != nil {
+				return filter, fmt.Errorf("invalid value %q for %s", part, field.JSONName)
+			}
+			values = append(values, value)
+		}
+		filter.Value = values
+	case repositories.Like:
+		filter.Value = raw
+	case repositories.IsNull:
+		if raw == "" {
+			raw = "true"
+		}
+		isNull, err := strconv.ParseBool(raw)
+		if err != nil {
+			return filter, fmt.Errorf("invalid value %q for %s[is_null]", raw, field.JSONName)
+		}
+		filter.Value = isNull
+	default:
+		return filter, fmt.Errorf("unknown operator %q for %s", op, field.JSONName)
+	}
+	return filter, nil
+}
+
+// resolveFields looks up the given JSON names among the column backed fields of T
+func resolveFields[T any](resource string, jsonNames []string) (map[string]*repositories.Field, error) {
+	fields, err := repositories.Fields[T]()
+	if err != nil {
+		return nil, err
+	}
+	resolved := map[string]*repositories.Field{}
+	for _, name := range jsonNames {
+		field, ok := fields[name]
+		if !ok || field.Column == "" {
+			return nil, fmt.Errorf("%s has no column for field %s", resource, name)
+		}
+		resolved[name] = field
+	}
+	return resolved, nil
+}
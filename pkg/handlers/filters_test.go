@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/model"
+	"github.com/arturoeanton/go-struc2fiber/pkg/repositories"
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+// filtersOf runs parseFilters on a request with the given query string
+func filtersOf(t *testing.T, h *Handler[model.Skill], query string) ([]repositories.Filter, error) {
+	t.Helper()
+	var filters []repositories.Filter
+	var err error
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		filters, err = h.parseFilters(c)
+		return nil
+	})
+	if _, testErr := app.Test(httptest.NewRequest("GET", "/?"+query, nil)); testErr != nil {
+		t.Fatal(testErr)
+	}
+	return filters, err
+}
+
+func TestParseFilters(t *testing.T) {
+	h := &Handler[model.Skill]{name: "skill"}
+	if err := h.SetFilterable("user_id", "name", "value"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  []repositories.Filter
+	}{
+		{"name=go", []repositories.Filter{{Column: "name", Operator: repositories.Eq, Value: "go"}}},
+		{"value[gte]=10", []repositories.Filter{{Column: "value", Operator: repositories.Gte, Value: int64(10)}}},
+		{"value[lt]=-3", []repositories.Filter{{Column: "value", Operator: repositories.Lt, Value: int64(-3)}}},
+		{"user_id[ne]=4", []repositories.Filter{{Column: "user_id", Operator: repositories.Ne, Value: int64(4)}}},
+		{"name[like]=go%25", []repositories.Filter{{Column: "name", Operator: repositories.Like, Value: "go%"}}},
+		{"user_id[in]=1,2,3", []repositories.Filter{{Column: "user_id", Operator: repositories.In, Value: []interface{}{int64(1), int64(2), int64(3)}}}},
+		{"name[in]=go,rust", []repositories.Filter{{Column: "name", Operator: repositories.In, Value: []interface{}{"go", "rust"}}}},
+		{"value[is_null]", []repositories.Filter{{Column: "value", Operator: repositories.IsNull, Value: true}}},
+		{"value[is_null]=false", []repositories.Filter{{Column: "value", Operator: repositories.IsNull, Value: false}}},
+		{"value[gt]=1&value[lte]=5", []repositories.Filter{
+			{Column: "value", Operator: repositories.Gt, Value: int64(1)},
+			{Column: "value", Operator: repositories.Lte, Value: int64(5)},
+		}},
+		// Reserved keys and keys naming no field are not filters
+		{"page=2&per_page=5&sort=-name&fields=name&cursor=&include=x", []repositories.Filter{}},
+		{"callback=x", []repositories.Filter{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := filtersOf(t, h, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFiltersInvalid(t *testing.T) {
+	h := &Handler[model.Skill]{name: "skill"}
+	if err := h.SetFilterable("user_id", "name", "value"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"id=1", "field id is not filterable"},
+		{"unknown[gte]=1", "field unknown is not filterable"},
+		{"value=abc", `invalid value "abc" for value`},
+		{"value[gte]=1.5", `invalid value "1.5" for value`},
+		{"user_id[in]=1,x", `invalid value "x" for user_id`},
+		{"value[is_null]=maybe", `invalid value "maybe" for value[is_null]`},
+		{"value[between]=1", `unknown operator "between" for value`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := filtersOf(t, h, tt.query)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	if err := h.SetFilterable("unknown"); err == nil {
+		t.Fatal("SetFilterable accepted an unknown field")
+	}
+}
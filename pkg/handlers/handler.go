@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"reflect"
 	"strconv"
@@ -18,17 +20,35 @@ type IHandler[T any] interface {
 	FxCreate(vals ...*validator.StructValidator) func(c *fiber.Ctx) error
 	DeleteByID(c *fiber.Ctx) error
 	FxUpdate(vals ...*validator.StructValidator) func(c *fiber.Ctx) error
+	FxPatch(vals ...*validator.StructValidator) func(c *fiber.Ctx) error
+	FxBulkCreate(vals ...*validator.StructValidator) func(c *fiber.Ctx) error
+	FxBulkUpdate(vals ...*validator.StructValidator) func(c *fiber.Ctx) error
+	DeleteByIDs(c *fiber.Ctx) error
 }
 
 type Handler[T any] struct {
 	service services.IService[T]
 	name    string
+	idParam string
+	// cursorColumn is the column cursor based listings are ordered by
+	cursorColumn string
+	// filterable holds the fields clients can filter on, by JSON name
+	filterable map[string]*repositories.Field
+	// sortable holds the fields clients can sort by, by JSON name
+	sortable map[string]*repositories.Field
+	// includes maps the associations clients can load, by JSON path, to GORM paths
+	includes map[string]string
+	// parent restricts the handler to the children of a parent item, nil when not nested
+	parent *parentScope
+	// associations are written along with the items
+	associations []*association
 }
 
 func NewHandler[T any]() *Handler[T] {
 
 	return &Handler[T]{
-		name: "items",
+		name:    "items",
+		idParam: "id",
 		service: services.NewService[T](
 			repositories.NewRepository[T](),
 		),
@@ -39,49 +59,183 @@ func (h *Handler[T]) Name() string {
 	return h.name
 }
 
+func (h *Handler[T]) SetName(name string) {
+	h.name = name
+}
+
+// SetIDParam sets the name of the route parameter holding the item ID
+func (h *Handler[T]) SetIDParam(idParam string) {
+	h.idParam = idParam
+}
+
+// SetCursorField sets the field, by JSON name, cursor based listings are
+// ordered by. Ties are broken by primary key, but the field must not be null.
+func (h *Handler[T]) SetCursorField(jsonName string) error {
+	fields, err := resolveFields[T](h.Name(), []string{jsonName})
+	if err != nil {
+		return err
+	}
+	h.cursorColumn = fields[jsonName].Column
+	return nil
+}
+
+// listQuery builds the repository query described by the query string
+func (h *Handler[T]) listQuery(c *fiber.Ctx) (*repositories.Query, *pagination, map[string]bool, error) {
+	page, err := parsePagination(c)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	query, keep, err := h.itemQuery(c)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	query.CursorColumn = h.cursorColumn
+	page.apply(query)
+
+	if query.Filters, err = h.parseFilters(c); err != nil {
+		return nil, nil, nil, err
+	}
+	if query.Sorts, err = h.parseSort(c); err != nil {
+		return nil, nil, nil, err
+	}
+	if page.byCursor && len(query.Sorts) > 0 {
+		return nil, nil, nil, errors.New("sort cannot be combined with cursor")
+	}
+	return query, page, keep, nil
+}
+
+// itemQuery builds the repository query shared by listings and single item
+// reads, along with the JSON keys to keep in the response
+func (h *Handler[T]) itemQuery(c *fiber.Ctx) (*repositories.Query, map[string]bool, error) {
+	query := &repositories.Query{}
+	columns, keep, err := h.parseFields(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	query.Columns = columns
+
+	preloads, keys, err := h.parseIncludes(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	query.Preloads = preloads
+	if keep != nil {
+		for _, key := range keys {
+			keep[key] = true
+		}
+	}
+	return query, keep, nil
+}
+
 func (h *Handler[T]) GetAll(c *fiber.Ctx) error {
-	items, _, err := h.service.GetAll()
+	parent, err := h.parentOf(c)
+	if err != nil {
+		return h.sendError(c, err)
+	}
+	query, page, keep, err := h.listQuery(c)
+	if err != nil {
+		return h.sendError(c, badInput(err))
+	}
+	parent.scope(query)
+
+	if page.byCursor {
+		items, cursors, err := h.service.GetAllByCursorContext(c.UserContext(), query)
+		if err != nil {
+			return h.sendError(c, err)
+		}
+		data, err := trimFields(items, keep)
+		if err != nil {
+			return h.sendError(c, err)
+		}
+		page.setCursorHeaders(c, cursors)
+		return c.Status(http.StatusOK).JSON(page.body(data, cursors))
+	}
+
+	items, total, err := h.service.GetAllByQueryContext(c.UserContext(), query)
+	if err != nil {
+		return h.sendError(c, err)
+	}
+	data, err := trimFields(items, keep)
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(map[string]string{
-			"error": "Failed to get " + h.Name(),
-		})
+		return h.sendError(c, err)
 	}
-	return c.Status(http.StatusOK).JSON(items)
+	page.setHeaders(c, total)
+	return c.Status(http.StatusOK).JSON(data)
 }
 
 func (h *Handler[T]) GetByID(c *fiber.Ctx) error {
-	id := c.Params("id")
-	item, err := h.service.GetByID(id)
+	parent, err := h.parentOf(c)
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(map[string]string{
-			"error": "Failed to get " + h.Name(),
-		})
+		return h.sendError(c, err)
+	}
+	id := c.Params(h.idParam)
+	query, keep, err := h.itemQuery(c)
+	if err != nil {
+		return h.sendError(c, badInput(err))
+	}
+	parent.scope(query)
+	item, err := h.service.GetByIDWithQueryContext(c.UserContext(), id, query)
+	if err != nil {
+		return h.sendError(c, err)
+	}
+	data, err := trimFields(item, keep)
+	if err != nil {
+		return h.sendError(c, err)
+	}
+	return c.Status(http.StatusOK).JSON(data)
+}
+
+// validate checks item against v and its children against the validators
+// of their associations, running the database checks bound to ctx. The
+// failures are reported in the locale requested through the Accept-Language
+// header.
+func (h *Handler[T]) validate(ctx context.Context, c *fiber.Ctx, v *validator.StructValidator, item *T) error {
+	errs := validator.ValidationErrors{}
+	if v != nil {
+		if valid, itemErrs := v.ValidateStructContext(ctx, item); !valid {
+			errs = append(errs, itemErrs...)
+		}
+	}
+	errs = append(errs, h.validateAssociations(ctx, item)...)
+	if len(errs) == 0 {
+		return nil
+	}
+	return h.validationError(c, errs)
+}
+
+// validationError localizes errs to the locale requested by the client. The
+// request fails as an internal error instead when a database check of errs
+// could not run.
+func (h *Handler[T]) validationError(c *fiber.Ctx, errs validator.ValidationErrors) error {
+	if err := errs.Err(); err != nil {
+		return err
 	}
-	return c.Status(http.StatusOK).JSON(item)
+	locale := validator.MatchLocale(c.Get(fiber.HeaderAcceptLanguage))
+	c.Set(fiber.HeaderContentLanguage, locale)
+	return validationFailed(errs.Localize(locale))
 }
 
 func (h *Handler[T]) FxCreate(vals ...*validator.StructValidator) func(c *fiber.Ctx) error {
 	return func(c *fiber.Ctx) error {
+		parent, err := h.parentOf(c)
+		if err != nil {
+			return h.sendError(c, err)
+		}
 		item := new(T)
 		if err := c.BodyParser(item); err != nil {
-			return c.Status(http.StatusInternalServerError).JSON(map[string]string{
-				"error": "Failed to get " + h.Name(),
-			})
-		}
-		if len(vals) > 0 && vals[0] != nil {
-			flagValid, errors := vals[0].ValidateStruct(item)
-			if !flagValid {
-				return c.Status(http.StatusBadRequest).JSON(map[string]any{
-					"error":  "Validation failed",
-					"fields": errors,
-				})
-			}
-		}
-		id, err := h.service.Create(item)
+			return h.sendError(c, badInput(err))
+		}
+		parent.set(item)
+		var v *validator.StructValidator
+		if len(vals) > 0 {
+			v = vals[0]
+		}
+		if err := h.validate(c.UserContext(), c, v, item); err != nil {
+			return h.sendError(c, err)
+		}
+		id, err := h.service.CreateContext(c.UserContext(), item)
 		if err != nil {
-			return c.Status(http.StatusInternalServerError).JSON(map[string]string{
-				"error": "Failed to get " + h.Name(),
-			})
+			return h.sendError(c, err)
 		}
 		reflect.ValueOf(item).Elem().FieldByName("ID").SetInt(id)
 		return c.Status(http.StatusOK).JSON(id)
@@ -89,55 +243,52 @@ func (h *Handler[T]) FxCreate(vals ...*validator.StructValidator) func(c *fiber.
 }
 
 func (h *Handler[T]) DeleteByID(c *fiber.Ctx) error {
-	id := c.Params("id")
-	rowAffected, err := h.service.Delete(id)
+	parent, err := h.parentOf(c)
+	if err != nil {
+		return h.sendError(c, err)
+	}
+	// The parent is checked by the DELETE statement itself
+	rowAffected, err := h.service.DeleteWithQueryContext(c.UserContext(), c.Params(h.idParam), parent.query())
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(map[string]string{
-			"error": "Failed to get " + h.Name(),
-		})
+		return h.sendError(c, err)
 	}
 	return c.Status(http.StatusOK).JSON(map[string]int64{"rows_affected": rowAffected})
 }
 
 func (h *Handler[T]) FxUpdate(vals ...*validator.StructValidator) func(c *fiber.Ctx) error {
 	return func(c *fiber.Ctx) error {
-		id := c.Params("id")
+		id := c.Params(h.idParam)
 		idInt, err := strconv.ParseInt(id, 10, 64)
 		if err != nil {
-			return c.Status(http.StatusBadRequest).JSON(map[string]string{
-				"error": "Invalid ID",
-			})
+			return h.sendError(c, badInput(errors.New("Invalid ID")))
+		}
+		parent, err := h.parentOf(c)
+		if err != nil {
+			return h.sendError(c, err)
 		}
-		_, err = h.service.GetByID(id)
+		_, err = h.service.GetByIDWithQueryContext(c.UserContext(), id, parent.query())
 		if err != nil {
-			return c.Status(http.StatusNotFound).JSON(map[string]string{
-				"error": h.Name() + " not found",
-			})
+			return h.sendError(c, err)
 		}
 		item := new(T)
 		if err := c.BodyParser(item); err != nil {
-			return c.Status(http.StatusInternalServerError).JSON(map[string]string{
-				"error": "Failed to get " + h.Name(),
-			})
+			return h.sendError(c, badInput(err))
 		}
 
 		reflect.ValueOf(item).Elem().FieldByName("ID").SetInt(idInt)
+		parent.set(item)
 
-		if len(vals) > 0 && vals[0] != nil {
-			flagValid, errors := vals[0].ValidateStruct(item)
-			if !flagValid {
-				return c.Status(http.StatusBadRequest).JSON(map[string]any{
-					"error":  "Validation failed",
-					"fields": errors,
-				})
-			}
+		var v *validator.StructValidator
+		if len(vals) > 0 {
+			v = vals[0]
+		}
+		if err := h.validate(c.UserContext(), c, v, item); err != nil {
+			return h.sendError(c, err)
 		}
 
-		rowAffected, err := h.service.Update(item)
+		rowAffected, err := h.service.UpdateContext(c.UserContext(), item)
 		if err != nil {
-			return c.Status(http.StatusInternalServerError).JSON(map[string]string{
-				"error": "Failed to get " + h.Name(),
-			})
+			return h.sendError(c, err)
 		}
 		return c.Status(http.StatusNoContent).JSON(rowAffected)
 	}
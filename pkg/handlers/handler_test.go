@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/model"
+	"github.com/arturoeanton/go-struc2fiber/pkg/repositories"
+	fiber "github.com/gofiber/fiber/v2"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB points repositories.DB to a new in-memory database holding the
+// tables of the models and the given rows
+func openTestDB(t *testing.T, rows ...interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: opens a distinct database
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(&model.InternalUser{}, &model.Skill{}, &model.DayOff{}, &model.Connect{}, &model.Apartment{}, &model.Checkin{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	repositories.DB = db
+	return db
+}
+
+// skills returns n skills of user 1 named s1, s2... with values 10, 20...
+func skills(n int) []interface{} {
+	rows := []interface{}{}
+	for i := 1; i <= n; i++ {
+		rows = append(rows, &model.Skill{UserID: 1, Name: "s" + strconv.Itoa(i), Value: i * 10})
+	}
+	return rows
+}
+
+// newSkillHandler returns a handler of skills named "skill"
+func newSkillHandler() *Handler[model.Skill] {
+	h := NewHandler[model.Skill]()
+	h.SetName("skill")
+	return h
+}
+
+// send runs a request against app and returns the response with its body.
+// Bodies are sent as JSON unless headers, given as name and value pairs,
+// set another content type.
+func send(t *testing.T, app *fiber.App, method, target, body string, headers ...string) (*http.Response, string) {
+	t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	if body != "" {
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(data)
+}
+
+// responseIDs returns the IDs of the items of a JSON array body
+func responseIDs(t *testing.T, body string) []int64 {
+	t.Helper()
+	var items []struct{ ID int64 }
+	if err := json.Unmarshal([]byte(body), &items); err != nil {
+		t.Fatalf("%v: %s", err, body)
+	}
+	ids := []int64{}
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
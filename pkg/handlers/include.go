@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/repositories"
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+// SetIncludes sets the associations, as dotted paths of JSON names like
+// "checkins_of_agent.apartment", clients can load with ?include=. Allowing a
+// nested path also allows each of its prefixes.
+func (h *Handler[T]) SetIncludes(jsonPaths ...string) error {
+	includes := map[string]string{}
+	for _, jsonPath := range jsonPaths {
+		segments := strings.Split(jsonPath, ".")
+		for i := range segments {
+			prefix := strings.Join(segments[:i+1], ".")
+			path, err := repositories.AssociationPath[T](prefix)
+			if err != nil {
+				return fmt.Errorf("%s: %v", h.Name(), err)
+			}
+			includes[prefix] = path
+		}
+	}
+	h.includes = includes
+	return nil
+}
+
+// parseIncludes reads ?include=skills,day_offs from the request and returns
+// the association paths to preload along with the JSON keys they fill
+func (h *Handler[T]) parseIncludes(c *fiber.Ctx) ([]string, []string, error) {
+	raw := c.Query("include")
+	if raw == "" {
+		return nil, nil, nil
+	}
+
+	preloads := []string{}
+	keys := []string{}
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		path, ok := h.includes[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("include %s is not allowed", name)
+		}
+		preloads = append(preloads, path)
+		keys = append(keys, strings.Split(name, ".")[0])
+	}
+	return preloads, keys, nil
+}
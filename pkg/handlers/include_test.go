@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/model"
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+// includesOf runs parseIncludes on a request with the given query string
+func includesOf(t *testing.T, h *Handler[model.InternalUser], query string) ([]string, []string, error) {
+	t.Helper()
+	var preloads, keys []string
+	var err error
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		preloads, keys, err = h.parseIncludes(c)
+		return nil
+	})
+	if _, testErr := app.Test(httptest.NewRequest("GET", "/?"+query, nil)); testErr != nil {
+		t.Fatal(testErr)
+	}
+	return preloads, keys, err
+}
+
+func TestParseIncludes(t *testing.T) {
+	h := &Handler[model.InternalUser]{name: "internal_users"}
+	if err := h.SetIncludes("skills", "checkins_of_agent.apartment"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query    string
+		preloads []string
+		keys     []string
+	}{
+		{"", nil, nil},
+		{"include=skills", []string{"Skills"}, []string{"skills"}},
+		// Allowing a nested path allows its prefixes
+		{"include=checkins_of_agent", []string{"CheckinsOfAgent"}, []string{"checkins_of_agent"}},
+		{"include=skills,%20checkins_of_agent.apartment", []string{"Skills", "CheckinsOfAgent.Apartment"}, []string{"skills", "checkins_of_agent"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			preloads, keys, err := includesOf(t, h, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(preloads, tt.preloads) || !reflect.DeepEqual(keys, tt.keys) {
+				t.Fatalf("got %v %v, want %v %v", preloads, keys, tt.preloads, tt.keys)
+			}
+		})
+	}
+
+	for _, query := range []string{"include=day_offs", "include=checkins_of_agent.agent", "include=Skills"} {
+		if _, _, err := includesOf(t, h, query); err == nil || !strings.Contains(err.Error(), "is not allowed") {
+			t.Errorf("%s: err = %v, want not allowed", query, err)
+		}
+	}
+
+	for _, path := range []string{"unknown", "skills.owner", "username"} {
+		if err := h.SetIncludes(path); err == nil {
+			t.Errorf("SetIncludes accepted %s", path)
+		}
+	}
+}
+
+func TestReadIncludes(t *testing.T) {
+	openTestDB(t,
+		&model.InternalUser{Username: "ana", Email: "ana@example.com"},
+		&model.InternalUser{Username: "bob", Email: "bob@example.com"},
+		&model.Skill{UserID: 1, Name: "go", Value: 30},
+		&model.Skill{UserID: 2, Name: "c", Value: 20},
+		&model.Skill{UserID: 1, Name: "zig", Value: 10},
+		&model.DayOff{UserID: 1, Start: "2024-01-01", End: "2024-01-02"},
+	)
+	h := NewHandler[model.InternalUser]()
+	h.SetName("internal_users")
+	if err := h.SetIncludes("skills"); err != nil {
+		t.Fatal(err)
+	}
+	app := fiber.New()
+	app.Get("/internal_users", h.GetAll)
+	app.Get("/internal_users/:id", h.GetByID)
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"/internal_users/1?fields=username&include=skills",
+			`{"skills":[{"id":1,"user_id":1,"name":"go","value":30},{"id":3,"user_id":1,"name":"zig","value":10}],"username":"ana"}`},
+		{"/internal_users?fields=username&include=skills&sort=",
+			`[{"skills":[{"id":1,"user_id":1,"name":"go","value":30},{"id":3,"user_id":1,"name":"zig","value":10}],"username":"ana"},` +
+				`{"skills":[{"id":2,"user_id":2,"name":"c","value":20}],"username":"bob"}]`},
+		// Associations are not loaded unless included
+		{"/internal_users/2?fields=id", `{"id":2}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			resp, body := send(t, app, "GET", tt.target, "")
+			if resp.StatusCode != fiber.StatusOK {
+				t.Fatalf("status = %d: %s", resp.StatusCode, body)
+			}
+			if body != tt.want {
+				t.Fatalf("body = %s, want %s", body, tt.want)
+			}
+		})
+	}
+
+	resp, body := send(t, app, "GET", "/internal_users/1?include=day_offs", "")
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("include not allowed: status = %d, want 400: %s", resp.StatusCode, body)
+	}
+}
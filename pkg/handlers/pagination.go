@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/repositories"
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+var (
+	// DefaultPerPage is the page size used when only ?page= is given
+	DefaultPerPage = 20
+	// MaxPerPage caps the page size a client can request
+	MaxPerPage = 100
+)
+
+// pagination holds the page requested through the query string
+type pagination struct {
+	limit    int
+	offset   int
+	byPage   bool
+	enabled  bool
+	byCursor bool
+	cursor   string
+}
+
+// cursorPage is the body returned by cursor based listings
+type cursorPage struct {
+	Data       interface{} `json:"data"`
+	NextCursor *string     `json:"next_cursor"`
+	PrevCursor *string     `json:"prev_cursor"`
+}
+
+// parsePagination reads ?page=/?per_page= or ?limit=/?offset= from the request.
+// Without any of them the whole list is returned, as before. A ?cursor= (empty
+// for the first page) switches to keyset pagination, see GetAllByCursor.
+func parsePagination(c *fiber.Ctx) (*pagination, error) {
+	p := &pagination{}
+
+	page, err := queryInt(c, "page", 0)
+	if err != nil {
+		return nil, err
+	}
+	perPage, err := queryInt(c, "per_page", 0)
+	if err != nil {
+		return nil, err
+	}
+	limit, err := queryInt(c, "limit", 0)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := queryInt(c, "offset", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case c.Context().QueryArgs().Has("cursor"):
+		if page > 0 || offset > 0 {
+			return nil, fmt.Errorf("cursor cannot be combined with page or offset")
+		}
+		p.byCursor = true
+		p.cursor = c.Query("cursor")
+		p.limit = perPage
+		if p.limit == 0 {
+			p.limit = limit
+		}
+		if p.limit == 0 {
+			p.limit = DefaultPerPage
+		}
+	case page > 0 || perPage > 0:
+		if page == 0 {
+			page = 1
+		}
+		if perPage == 0 {
+			perPage = DefaultPerPage
+		}
+		p.byPage = true
+		p.limit = perPage
+	case limit > 0 || offset > 0:
+		if limit == 0 {
+			limit = DefaultPerPage
+		}
+		p.limit = limit
+		p.offset = offset
+	default:
+		return p, nil
+	}
+
+	if p.limit > MaxPerPage {
+		p.limit = MaxPerPage
+	}
+	if p.byPage {
+		// The offset of the page must fit in an int
+		if page-1 > (math.MaxInt-p.limit)/p.limit {
+			return nil, fmt.Errorf("page %d is out of range", page)
+		}
+		p.offset = (page - 1) * p.limit
+	}
+	p.enabled = true
+	return p, nil
+}
+
+// apply copies the pagination into a repository query
+func (p *pagination) apply(q *repositories.Query) {
+	q.Limit = p.limit
+	q.Offset = p.offset
+	q.Cursor = p.cursor
+}
+
+// setHeaders writes X-Total-Count and, when paginating, the RFC 8288 Link header
+func (p *pagination) setHeaders(c *fiber.Ctx, total int64) {
+	c.Set("X-Total-Count", strconv.FormatInt(total, 10))
+	if !p.enabled {
+		return
+	}
+
+	last := 0
+	if total > 0 {
+		last = int((total - 1) / int64(p.limit) * int64(p.limit))
+	}
+	links := []string{p.link(c, 0, "first")}
+	if p.offset > 0 {
+		prev := p.offset - p.limit
+		if prev < 0 {
+			prev = 0
+		}
+		links = append(links, p.link(c, prev, "prev"))
+	}
+	if int64(p.offset+p.limit) < total {
+		links = append(links, p.link(c, p.offset+p.limit, "next"))
+	}
+	links = append(links, p.link(c, last, "last"))
+
+	c.Set(fiber.HeaderLink, strings.Join(links, ", "))
+}
+
+// setCursorHeaders writes the Link header of a cursor based listing
+func (p *pagination) setCursorHeaders(c *fiber.Ctx, cursors *repositories.Cursors) {
+	links := []string{}
+	if cursors.Prev != "" {
+		links = append(links, p.cursorLink(c, cursors.Prev, "prev"))
+	}
+	if cursors.Next != "" {
+		links = append(links, p.cursorLink(c, cursors.Next, "next"))
+	}
+	if len(links) > 0 {
+		c.Set(fiber.HeaderLink, strings.Join(links, ", "))
+	}
+}
+
+// body wraps a cursor based listing with the cursors of the pages around it
+func (p *pagination) body(items interface{}, cursors *repositories.Cursors) *cursorPage {
+	page := &cursorPage{Data: items}
+	if cursors.Next != "" {
+		page.NextCursor = &cursors.Next
+	}
+	if cursors.Prev != "" {
+		page.PrevCursor = &cursors.Prev
+	}
+	return page
+}
+
+func (p *pagination) cursorLink(c *fiber.Ctx, cursor string, rel string) string {
+	values, _ := url.ParseQuery(string(c.Context().QueryArgs().QueryString()))
+	values.Set("cursor", cursor)
+	return fmt.Sprintf("<%s%s?%s>; rel=\"%s\"", c.BaseURL(), c.Path(), values.Encode(), rel)
+}
+
+// link builds a Link entry pointing to the page starting at offset
+func (p *pagination) link(c *fiber.Ctx, offset int, rel string) string {
+	values, _ := url.ParseQuery(string(c.Context().QueryArgs().QueryString()))
+	if p.byPage {
+		values.Set("page", strconv.Itoa(offset/p.limit+1))
+		values.Set("per_page", strconv.Itoa(p.limit))
+	} else {
+		values.Set("limit", strconv.Itoa(p.limit))
+		values.Set("offset", strconv.Itoa(offset))
+	}
+	return fmt.Sprintf("<%s%s?%s>; rel=\"%s\"", c.BaseURL(), c.Path(), values.Encode(), rel)
+}
+
+// queryInt reads a non negative integer from the query string
+func queryInt(c *fiber.Ctx, key string, fallback int) (int, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s %q", key, raw)
+	}
+	return n, nil
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+// paginationOf runs parsePagination on a request with the given query string
+func paginationOf(t *testing.T, query string) (*pagination, error) {
+	t.Helper()
+	var p *pagination
+	var err error
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		p, err = parsePagination(c)
+		return nil
+	})
+	if _, testErr := app.Test(httptest.NewRequest("GET", "/?"+query, nil)); testErr != nil {
+		t.Fatal(testErr)
+	}
+	return p, err
+}
+
+func TestParsePagination(t *testing.T) {
+	tests := []struct {
+		query string
+		want  pagination
+	}{
+		{"", pagination{}},
+		{"page=2", pagination{limit: 20, offset: 20, byPage: true, enabled: true}},
+		{"per_page=5", pagination{limit: 5, offset: 0, byPage: true, enabled: true}},
+		{"page=3&per_page=5", pagination{limit: 5, offset: 10, byPage: true, enabled: true}},
+		{"limit=5", pagination{limit: 5, enabled: true}},
+		{"offset=7", pagination{limit: 20, offset: 7, enabled: true}},
+		{"limit=5&offset=7", pagination{limit: 5, offset: 7, enabled: true}},
+		// Page sizes are capped to MaxPerPage, pages keep their position
+		{"per_page=500", pagination{limit: 100, offset: 0, byPage: true, enabled: true}},
+		{"page=2&per_page=500", pagination{limit: 100, offset: 100, byPage: true, enabled: true}},
+		{"limit=1000&offset=3", pagination{limit: 100, offset: 3, enabled: true}},
+		{"cursor=", pagination{limit: 20, byCursor: true, enabled: true}},
+		{"cursor=abc&per_page=5", pagination{limit: 5, byCursor: true, cursor: "abc", enabled: true}},
+		{"cursor=abc&limit=500", pagination{limit: 100, byCursor: true, cursor: "abc", enabled: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := paginationOf(t, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Fatalf("got %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePaginationInvalid(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"page=abc", `invalid page "abc"`},
+		{"per_page=-1", `invalid per_page "-1"`},
+		{"limit=1.5", `invalid limit "1.5"`},
+		{"offset=-3", `invalid offset "-3"`},
+		{"cursor=&page=2", "cursor cannot be combined with page or offset"},
+		{"cursor=&offset=2", "cursor cannot be combined with page or offset"},
+		{"page=9223372036854775807", "page 9223372036854775807 is out of range"},
+		{"page=92233720368547759&per_page=1000", "page 92233720368547759 is out of range"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := paginationOf(t, tt.query)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetAllPaginationHeaders(t *testing.T) {
+	openTestDB(t, skills(7)...)
+	app := fiber.New()
+	app.Get("/skill", newSkillHandler().GetAll)
+
+	tests := []struct {
+		query string
+		ids   []int64
+		link  string
+	}{
+		{"", []int64{1, 2, 3, 4, 5, 6, 7}, ""},
+		{"page=1&per_page=3", []int64{1, 2, 3}, `<http://example.com/skill?page=1&per_page=3>; rel="first", ` +
+			`<http://example.com/skill?page=2&per_page=3>; rel="next", ` +
+			`<http://example.com/skill?page=3&per_page=3>; rel="last"`},
+		{"page=2&per_page=3", []int64{4, 5, 6}, `<http://example.com/skill?page=1&per_page=3>; rel="first", ` +
+			`<http://example.com/skill?page=1&per_page=3>; rel="prev", ` +
+			`<http://example.com/skill?page=3&per_page=3>; rel="next", ` +
+			`<http://example.com/skill?page=3&per_page=3>; rel="last"`},
+		{"page=3&per_page=3", []int64{7}, `<http://example.com/skill?page=1&per_page=3>; rel="first", ` +
+			`<http://example.com/skill?page=2&per_page=3>; rel="prev", ` +
+			`<http://example.com/skill?page=3&per_page=3>; rel="last"`},
+		{"limit=2&offset=1", []int64{2, 3}, `<http://example.com/skill?limit=2&offset=0>; rel="first", ` +
+			`<http://example.com/skill?limit=2&offset=0>; rel="prev", ` +
+			`<http://example.com/skill?limit=2&offset=3>; rel="next", ` +
+			`<http://example.com/skill?limit=2&offset=6>; rel="last"`},
+		{"page=9&per_page=3", []int64{}, `<http://example.com/skill?page=1&per_page=3>; rel="first", ` +
+			`<http://example.com/skill?page=8&per_page=3>; rel="prev", ` +
+			`<http://example.com/skill?page=3&per_page=3>; rel="last"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			resp, body := send(t, app, "GET", "/skill?"+tt.query, "")
+			if resp.StatusCode != fiber.StatusOK {
+				t.Fatalf("status = %d: %s", resp.StatusCode, body)
+			}
+			if got := resp.Header.Get("X-Total-Count"); got != "7" {
+				t.Errorf("X-Total-Count = %q, want 7", got)
+			}
+			if got := resp.Header.Get(fiber.HeaderLink); got != tt.link {
+				t.Errorf("Link = %q, want %q", got, tt.link)
+			}
+			ids := responseIDs(t, body)
+			if !reflect.DeepEqual(ids, tt.ids) {
+				t.Errorf("ids = %v, want %v", ids, tt.ids)
+			}
+		})
+	}
+}
+
+func TestGetAllPaginationInvalid(t *testing.T) {
+	openTestDB(t)
+	app := fiber.New()
+	app.Get("/skill", newSkillHandler().GetAll)
+
+	for _, target := range []string{"/skill?per_page=x", "/skill?page=9223372036854775807"} {
+		resp, body := send(t, app, "GET", target, "")
+		if resp.StatusCode != fiber.StatusBadRequest {
+			t.Fatalf("%s: status = %d, want 400: %s", target, resp.StatusCode, body)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/repositories"
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+// parentScope restricts a handler to the children of one parent item, as in
+// /internal_users/:user_id/skills
+type parentScope struct {
+	// name is the parent resource name and param the route parameter holding its ID
+	name  string
+	param string
+	// field is the foreign key of the children referencing the parent
+	field *repositories.Field
+	// exists fails with a not found error when the parent does not exist
+	exists func(ctx context.Context, id interface{}) error
+}
+
+// parentRef is the parent item of a request to a nested handler. Its
+// methods do nothing on a nil parentRef, so handlers not nested need no checks.
+type parentRef struct {
+	field *repositories.Field
+	id    interface{}
+}
+
+// SetParent nests the handler under the parent resource name: every query is
+// restricted to the items whose foreignKey, a JSON name, holds the ID found in
+// the route parameter param, and that value is forced on the items written.
+// exists is called to report a missing parent as not found.
+func (h *Handler[T]) SetParent(name, param, foreignKey string, exists func(ctx context.Context, id interface{}) error) error {
+	fields, err := resolveFields[T](h.Name(), []string{foreignKey})
+	if err != nil {
+		return err
+	}
+	h.parent = &parentScope{name: name, param: param, field: fields[foreignKey], exists: exists}
+	return nil
+}
+
+// parentOf returns the parent item of the request, nil when the handler is
+// not nested. A missing parent is reported as not found.
+func (h *Handler[T]) parentOf(c *fiber.Ctx) (*parentRef, error) {
+	if h.parent == nil {
+		return nil, nil
+	}
+	raw := c.Params(h.parent.param)
+	id, err := repositories.ParseValue(h.parent.field.Type, raw)
+	if err != nil {
+		return nil, badInput(fmt.Errorf("invalid %s %q", h.parent.param, raw))
+	}
+	if err := h.parent.exists(c.UserContext(), id); err != nil {
+		if repositories.KindOf(err) == repositories.KindNotFound {
+			return nil, &repositories.Error{Kind: repositories.KindNotFound, Message: h.parent.name + " not found", Err: err}
+		}
+		return nil, err
+	}
+	return &parentRef{field: h.parent.field, id: id}, nil
+}
+
+// scope restricts q to the children of the parent
+func (p *parentRef) scope(q *repositories.Query) {
+	if p == nil {
+		return
+	}
+	q.Filters = append(q.Filters, repositories.Filter{Column: p.field.Column, Operator: repositories.Eq, Value: p.id})
+}
+
+// query returns a query restricted to the children of the parent
+func (p *parentRef) query() *repositories.Query {
+	q := &repositories.Query{}
+	p.scope(q)
+	return q
+}
+
+// set makes item a child of the parent
+func (p *parentRef) set(item interface{}) {
+	if p == nil {
+		return
+	}
+	field := reflect.ValueOf(item).Elem().FieldByName(p.field.Name)
+	value := reflect.ValueOf(p.id)
+	for field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		field = field.Elem()
+	}
+	field.Set(value.Convert(field.Type()))
+}
+
+// owns reports whether item is a child of the parent
+func (p *parentRef) owns(item interface{}) bool {
+	if p == nil {
+		return true
+	}
+	field := reflect.Indirect(reflect.ValueOf(item).Elem().FieldByName(p.field.Name))
+	if !field.IsValid() {
+		return false
+	}
+	return field.Convert(reflect.TypeOf(p.id)).Interface() == p.id
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/model"
+	"github.com/arturoeanton/go-struc2fiber/pkg/repositories"
+	fiber "github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// newNestedSkillHandler returns a handler of the skills of the user in the
+// :user_id route parameter
+func newNestedSkillHandler(t *testing.T) *Handler[model.Skill] {
+	t.Helper()
+	h := newSkillHandler()
+	users := repositories.NewRepository[model.InternalUser]()
+	err := h.SetParent("internal_users", "user_id", "user_id", func(ctx context.Context, id interface{}) error {
+		_, err := users.GetByIDContext(ctx, id)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestNestedNotFound(t *testing.T) {
+	db := openTestDB(t,
+		&model.InternalUser{Username: "ana", Email: "ana@example.com"},
+		&model.InternalUser{Username: "bob", Email: "bob@example.com"},
+		&model.Skill{UserID: 1, Name: "go", Value: 30},
+		&model.Skill{UserID: 2, Name: "c", Value: 20},
+	)
+	h := newNestedSkillHandler(t)
+	app := fiber.New()
+	app.Get("/internal_users/:user_id/skills", h.GetAll)
+	app.Get("/internal_users/:user_id/skills/:id", h.GetByID)
+	app.Post("/internal_users/:user_id/skills", h.FxCreate())
+	app.Put("/internal_users/:user_id/skills/:id", h.FxUpdate())
+	app.Patch("/internal_users/:user_id/skills/:id", h.FxPatch())
+	app.Delete("/internal_users/:user_id/skills/:id", h.DeleteByID)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		detail string
+	}{
+		{"list of missing parent", "GET", "/internal_users/9/skills", "", "internal_users not found"},
+		{"get of missing parent", "GET", "/internal_users/9/skills/1", "", "internal_users not found"},
+		{"create under missing parent", "POST", "/internal_users/9/skills", `{"name":"zig"}`, "internal_users not found"},
+		{"update of missing parent", "PUT", "/internal_users/9/skills/1", `{"name":"zig"}`, "internal_users not found"},
+		{"patch of missing parent", "PATCH", "/internal_users/9/skills/1", `{"name":"zig"}`, "internal_users not found"},
+		{"delete of missing parent", "DELETE", "/internal_users/9/skills/1", "", "internal_users not found"},
+		// Skill 2 belongs to user 2
+		{"get of other parent", "GET", "/internal_users/1/skills/2", "", "skill not found"},
+		{"update of other parent", "PUT", "/internal_users/1/skills/2", `{"name":"zig"}`, "skill not found"},
+		{"patch of other parent", "PATCH", "/internal_users/1/skills/2", `{"name":"zig"}`, "skill not found"},
+		{"delete of other parent", "DELETE", "/internal_users/1/skills/2", "", "skill not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := send(t, app, tt.method, tt.target, tt.body)
+			if resp.StatusCode != fiber.StatusNotFound || !strings.Contains(body, `"detail":"`+tt.detail+`"`) {
+				t.Fatalf("got %d %s, want 404 %q", resp.StatusCode, body, tt.detail)
+			}
+		})
+	}
+
+	var stored []model.Skill
+	if err := db.Order("id").Find(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 2 || stored[0].Name != "go" || stored[1].Name != "c" || stored[1].UserID != 2 {
+		t.Fatalf("skills = %+v, want them unchanged", stored)
+	}
+}
+
+func TestNestedDelete(t *testing.T) {
+	db := openTestDB(t,
+		&model.InternalUser{Username: "ana", Email: "ana@example.com"},
+		&model.Skill{UserID: 1, Name: "go", Value: 30},
+	)
+	h := newNestedSkillHandler(t)
+	app := fiber.New()
+	app.Delete("/internal_users/:user_id/skills/:id", h.DeleteByID)
+
+	// Record the DELETE statements to check they are scoped to the parent
+	statements := []string{}
+	if err := db.Callback().Delete().After("gorm:delete").Register("test:statements", func(tx *gorm.DB) {
+		statements = append(statements, tx.Statement.SQL.String())
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, body := send(t, app, "DELETE", "/internal_users/1/skills/1", "")
+	if resp.StatusCode != fiber.StatusOK || body != `{"rows_affected":1}` {
+		t.Fatalf("got %d %s", resp.StatusCode, body)
+	}
+	if len(statements) != 1 || !strings.Contains(statements[0], "`user_id` = ?") {
+		t.Fatalf("statements = %q, want one DELETE restricted to the parent", statements)
+	}
+}
@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/patch"
+	"github.com/arturoeanton/go-struc2fiber/pkg/repositories"
+	"github.com/arturoeanton/go-struc2fiber/pkg/validator"
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+const (
+	// MIMEMergePatchJSON is the content type of RFC 7386 merge patches
+	MIMEMergePatchJSON = "application/merge-patch+json"
+	// MIMEJSONPatchJSON is the content type of RFC 6902 patches
+	MIMEJSONPatchJSON = "application/json-patch+json"
+)
+
+// acceptPatch lists the PATCH bodies understood by FxPatch
+var acceptPatch = strings.Join([]string{fiber.MIMEApplicationJSON, MIMEMergePatchJSON, MIMEJSONPatchJSON}, ", ")
+
+// FxPatch updates an item from a PATCH body. Plain JSON bodies are partial
+// updates: only the fields present are validated and written. Merge patches
+// and JSON patches are applied to the stored item, which is then validated
+// as a whole and saved in the same transaction.
+func (h *Handler[T]) FxPatch(vals ...*validator.StructValidator) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		id := c.Params(h.idParam)
+		idInt, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return h.sendError(c, badInput(errors.New("Invalid ID")))
+		}
+		var v *validator.StructValidator
+		if len(vals) > 0 {
+			v = vals[0]
+		}
+
+		parent, err := h.parentOf(c)
+		if err != nil {
+			return h.sendError(c, err)
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(c.Get(fiber.HeaderContentType))
+		switch mediaType {
+		case fiber.MIMEApplicationJSON, "":
+		case MIMEMergePatchJSON, MIMEJSONPatchJSON:
+			return h.applyPatch(c, id, idInt, parent, mediaType, v)
+		default:
+			c.Set("Accept-Patch", acceptPatch)
+			return ErrorRenderer(c, NewProblem(c, http.StatusUnsupportedMediaType, "unsupported_media_type",
+				"PATCH bodies must be one of "+acceptPatch))
+		}
+
+		item, err := h.service.GetByIDWithQueryContext(c.UserContext(), id, parent.query())
+		if err != nil {
+			return h.sendError(c, err)
+		}
+
+		body := map[string]json.RawMessage{}
+		if err := json.Unmarshal(c.Body(), &body); err != nil {
+			return h.sendError(c, badInput(err))
+		}
+		if err := json.Unmarshal(c.Body(), item); err != nil {
+			return h.sendError(c, badInput(err))
+		}
+		reflect.ValueOf(item).Elem().FieldByName("ID").SetInt(idInt)
+		parent.set(item)
+
+		present, columns, err := h.patchColumns(body)
+		if err != nil {
+			return h.sendError(c, err)
+		}
+
+		if err := h.validatePartial(c.UserContext(), c, v, item, present); err != nil {
+			return h.sendError(c, err)
+		}
+
+		rowAffected, err := h.service.UpdateColumnsContext(c.UserContext(), item, columns)
+		if err != nil {
+			return h.sendError(c, err)
+		}
+		return c.Status(http.StatusNoContent).JSON(rowAffected)
+	}
+}
+
+// applyPatch applies a merge patch or a JSON patch to the stored item
+func (h *Handler[T]) applyPatch(c *fiber.Ctx, id string, idInt int64, parent *parentRef, mediaType string, v *validator.StructValidator) error {
+	var apply func(doc interface{}) (interface{}, error)
+	if mediaType == MIMEMergePatchJSON {
+		mergePatch, err := patch.Decode(c.Body())
+		if err != nil {
+			return h.sendError(c, badInput(err))
+		}
+		apply = func(doc interface{}) (interface{}, error) {
+			return patch.Merge(doc, mergePatch), nil
+		}
+	} else {
+		ops, err := patch.ParseOperations(c.Body())
+		if err != nil {
+			return h.sendError(c, badInput(err))
+		}
+		apply = func(doc interface{}) (interface{}, error) {
+			return patch.Apply(doc, ops)
+		}
+	}
+
+	fields, err := columnFields[T]()
+	if err != nil {
+		return h.sendError(c, err)
+	}
+
+	rowAffected, err := h.service.ModifyContext(c.UserContext(), id, func(ctx context.Context, item *T) error {
+		if !parent.owns(item) {
+			return repositories.ErrNotFound
+		}
+		doc, err := toDocument(item, fields)
+		if err != nil {
+			return err
+		}
+		patched, err := apply(doc)
+		if err != nil {
+			return patchFailed(err)
+		}
+		obj, ok := patched.(map[string]interface{})
+		if !ok {
+			return badInput(errors.New("patched document must be an object"))
+		}
+		if err := fromDocument(item, obj, fields); err != nil {
+			return badInput(err)
+		}
+		reflect.ValueOf(item).Elem().FieldByName("ID").SetInt(idInt)
+		parent.set(item)
+		return h.validate(ctx, c, v, item)
+	})
+	if err != nil {
+		return h.sendError(c, err)
+	}
+	return c.Status(http.StatusNoContent).JSON(rowAffected)
+}
+
+// patchFailed classifies an error applying a patch: malformed patches are bad
+// input, patches not matching the stored item are conflicts
+func patchFailed(err error) error {
+	if errors.Is(err, patch.ErrConflict) {
+		return &repositories.Error{Kind: repositories.KindConflict, Message: err.Error(), Err: err}
+	}
+	return badInput(err)
+}
+
+// columnFields returns the fields of T backed by a column, by JSON name
+func columnFields[T any]() (map[string]*repositories.Field, error) {
+	fields, err := repositories.Fields[T]()
+	if err != nil {
+		return nil, err
+	}
+	for name, field := range fields {
+		if field.Column == "" {
+			delete(fields, name)
+		}
+	}
+	return fields, nil
+}
+
+// toDocument encodes the given fields of item as a JSON object, zero values
+// included so patches can target them
+func toDocument[T any](item *T, fields map[string]*repositories.Field) (map[string]interface{}, error) {
+	val := reflect.ValueOf(item).Elem()
+	doc := map[string]interface{}{}
+	for name, field := range fields {
+		data, err := json.Marshal(val.FieldByName(field.Name).Interface())
+		if err != nil {
+			return nil, err
+		}
+		if doc[name], err = patch.Decode(data); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// fromDocument sets the given fields of item from doc, fields missing in doc
+// are reset to their zero value
+func fromDocument[T any](item *T, doc map[string]interface{}, fields map[string]*repositories.Field) error {
+	val := reflect.ValueOf(item).Elem()
+	for name, field := range fields {
+		fieldVal := val.FieldByName(field.Name)
+		fieldVal.Set(reflect.Zero(fieldVal.Type()))
+		value, ok := doc[name]
+		if !ok {
+			continue
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, fieldVal.Addr().Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// patchColumns returns the JSON fields of body backed by a column along with
+// those columns. The primary key, associations and unknown keys are skipped.
+func (h *Handler[T]) patchColumns(body map[string]json.RawMessage) ([]string, []string, error) {
+	s, err := repositories.ParseSchema(repositories.CreateNewElement[T]())
+	if err != nil {
+		return nil, nil, err
+	}
+	fields, err := columnFields[T]()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	present := []string{}
+	columns := []string{}
+	for key := range body {
+		field, ok := fields[key]
+		if !ok {
+			continue
+		}
+		if pk := s.PrioritizedPrimaryField; pk != nil && pk.DBName == field.Column {
+			continue
+		}
+		present = append(present, key)
+		columns = append(columns, field.Column)
+	}
+	if len(columns) == 0 {
+		return nil, nil, badInput(errors.New("body has no fields to update"))
+	}
+	return present, columns, nil
+}
+
+// validatePartial checks the given fields of item only, running the database
+// checks bound to ctx
+func (h *Handler[T]) validatePartial(ctx context.Context, c *fiber.Ctx, v *validator.StructValidator, item *T, fields []string) error {
+	if v == nil {
+		return nil
+	}
+	valid, errs := v.ValidateStructPartialContext(ctx, item, fields)
+	if valid {
+		return nil
+	}
+	return h.validationError(c, errs)
+}
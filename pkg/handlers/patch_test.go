@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/model"
+	"github.com/arturoeanton/go-struc2fiber/pkg/validator"
+	fiber "github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func TestPatchColumns(t *testing.T) {
+	h := &Handler[model.InternalUser]{name: "internal_users"}
+
+	tests := []struct {
+		body    string
+		present []string
+		columns []string
+	}{
+		{`{"username":"ana"}`, []string{"username"}, []string{"username"}},
+		{`{"username":"ana","email":null}`, []string{"email", "username"}, []string{"email", "username"}},
+		// The primary key, associations and unknown keys are skipped
+		{`{"id":7,"role":"admin","skills":[],"unknown":"x"}`, []string{"role"}, []string{"user_role"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			body := map[string]json.RawMessage{}
+			if err := json.Unmarshal([]byte(tt.body), &body); err != nil {
+				t.Fatal(err)
+			}
+			present, columns, err := h.patchColumns(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Strings(present)
+			sort.Strings(columns)
+			if !reflect.DeepEqual(present, tt.present) || !reflect.DeepEqual(columns, tt.columns) {
+				t.Fatalf("got %v %v, want %v %v", present, columns, tt.present, tt.columns)
+			}
+		})
+	}
+
+	for _, body := range []map[string]json.RawMessage{{}, {"id": json.RawMessage("1")}, {"skills": json.RawMessage("[]")}} {
+		if _, _, err := h.patchColumns(body); err == nil || !strings.Contains(err.Error(), "no fields to update") {
+			t.Errorf("%v: err = %v, want no fields to update", body, err)
+		}
+	}
+}
+
+func TestPartialPatch(t *testing.T) {
+	db := openTestDB(t, &model.Skill{UserID: 1, Name: "go", Value: 30})
+	v := validator.NewStructValidator()
+	err := v.LoadSchemaFromYAML(`
+name: Skill
+rules:
+  - field: name
+    type: string
+    required: true
+    minLength: 2
+  - field: value
+    type: integer
+    min: 0
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := fiber.New()
+	app.Patch("/skill/:id", newSkillHandler().FxPatch(v))
+
+	// Record the statements run to check only the columns sent are written
+	statements := []string{}
+	if err := db.Callback().Update().After("gorm:update").Register("test:statements", func(tx *gorm.DB) {
+		statements = append(statements, tx.Statement.SQL.String())
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		sql    string
+		want   model.Skill
+	}{
+		// Rules of the fields not sent, like the required name, are not checked
+		{"one field", `{"value":50}`, fiber.StatusNoContent, "UPDATE `skill` SET `value`=", model.Skill{ID: 1, UserID: 1, Name: "go", Value: 50}},
+		{"zero value", `{"value":0}`, fiber.StatusNoContent, "UPDATE `skill` SET `value`=", model.Skill{ID: 1, UserID: 1, Name: "go", Value: 0}},
+		{"two fields", `{"name":"zig","value":10}`, fiber.StatusNoContent, "UPDATE `skill` SET `name`=?,`value`=", model.Skill{ID: 1, UserID: 1, Name: "zig", Value: 10}},
+		{"id ignored", `{"id":9,"name":"go"}`, fiber.StatusNoContent, "UPDATE `skill` SET `name`=", model.Skill{ID: 1, UserID: 1, Name: "go", Value: 10}},
+		{"invalid field", `{"name":"c"}`, fiber.StatusUnprocessableEntity, "", model.Skill{ID: 1, UserID: 1, Name: "go", Value: 10}},
+		{"no fields", `{"unknown":1}`, fiber.StatusBadRequest, "", model.Skill{ID: 1, UserID: 1, Name: "go", Value: 10}},
+		{"malformed", `{"name":`, fiber.StatusBadRequest, "", model.Skill{ID: 1, UserID: 1, Name: "go", Value: 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statements = statements[:0]
+			resp, body := send(t, app, "PATCH", "/skill/1", tt.body)
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.status, body)
+			}
+			if tt.sql != "" && (len(statements) != 1 || !strings.HasPrefix(statements[0], tt.sql)) {
+				t.Errorf("statements = %q, want one starting with %q", statements, tt.sql)
+			}
+			if tt.sql == "" && len(statements) != 0 {
+				t.Errorf("statements = %q, want none", statements)
+			}
+			var got model.Skill
+			if err := db.First(&got, 1).Error; err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("stored %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	for target, want := range map[string]int{"/skill/9": fiber.StatusNotFound, "/skill/abc": fiber.StatusBadRequest} {
+		resp, body := send(t, app, "PATCH", target, `{"value":1}`)
+		if resp.StatusCode != want {
+			t.Errorf("%s: status = %d, want %d: %s", target, resp.StatusCode, want, body)
+		}
+	}
+}
+
+func TestPatchDocuments(t *testing.T) {
+	db := openTestDB(t, &model.Skill{UserID: 1, Name: "go", Value: 30}, &model.Skill{UserID: 1, Name: "zig", Value: 10})
+	v := validator.NewStructValidator()
+	// The unique check runs in the transaction holding the row: on the
+	// single connection of the test database it would block otherwise
+	err := v.LoadSchemaFromYAML(`
+name: Skill
+rules:
+  - field: name
+    type: string
+    minLength: 1
+    unique: true
+  - field: value
+    type: integer
+    max: 100
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := fiber.New()
+	app.Patch("/skill/:id", newSkillHandler().FxPatch(v))
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+		want        model.Skill
+	}{
+		{"merge", MIMEMergePatchJSON, `{"value":40}`, fiber.StatusNoContent, model.Skill{ID: 1, UserID: 1, Name: "go", Value: 40}},
+		{"merge removes", MIMEMergePatchJSON, `{"value":null}`, fiber.StatusNoContent, model.Skill{ID: 1, UserID: 1, Name: "go", Value: 0}},
+		{"json patch", MIMEJSONPatchJSON, `[{"op":"test","path":"/name","value":"go"},{"op":"replace","path":"/value","value":50}]`,
+			fiber.StatusNoContent, model.Skill{ID: 1, UserID: 1, Name: "go", Value: 50}},
+		{"failed test", MIMEJSONPatchJSON, `[{"op":"test","path":"/name","value":"c"},{"op":"replace","path":"/value","value":60}]`,
+			fiber.StatusConflict, model.Skill{ID: 1, UserID: 1, Name: "go", Value: 50}},
+		{"malformed", MIMEJSONPatchJSON, `[{"op":"jump","path":"/value"}]`, fiber.StatusBadRequest, model.Skill{ID: 1, UserID: 1, Name: "go", Value: 50}},
+		// The patched item is validated as a whole
+		{"invalid", MIMEMergePatchJSON, `{"value":500}`, fiber.StatusUnprocessableEntity, model.Skill{ID: 1, UserID: 1, Name: "go", Value: 50}},
+		{"removes name", MIMEJSONPatchJSON, `[{"op":"remove","path":"/name"}]`, fiber.StatusUnprocessableEntity, model.Skill{ID: 1, UserID: 1, Name: "go", Value: 50}},
+		{"taken", MIMEMergePatchJSON, `{"name":"zig"}`, fiber.StatusUnprocessableEntity, model.Skill{ID: 1, UserID: 1, Name: "go", Value: 50}},
+		{"unsupported", fiber.MIMETextPlain, `value=1`, fiber.StatusUnsupportedMediaType, model.Skill{ID: 1, UserID: 1, Name: "go", Value: 50}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := send(t, app, "PATCH", "/skill/1", tt.body, fiber.HeaderContentType, tt.contentType)
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.status, body)
+			}
+			var got model.Skill
+			if err := db.First(&got, 1).Error; err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("stored %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/validator"
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+// MIMESchemaJSON is the content type of JSON Schema documents
+const MIMESchemaJSON = "application/schema+json"
+
+// FxSchema serves the validation schema of the resource as JSON Schema. vals
+// are the create and update validators, ?for=update selects the latter.
+func (h *Handler[T]) FxSchema(vals ...*validator.StructValidator) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		index := 0
+		switch c.Query("for", "create") {
+		case "create":
+		case "update":
+			index = 1
+		default:
+			return h.sendError(c, badInput(errors.New("for must be create or update")))
+		}
+		if index >= len(vals) || vals[index] == nil || vals[index].GetSchema() == nil {
+			return ErrorRenderer(c, NewProblem(c, http.StatusNotFound, "not_found", h.Name()+" has no validation schema"))
+		}
+
+		schema := validator.ToJSONSchema(vals[index].GetSchema())
+		if schema.Title == "" {
+			schema.Title = h.Name()
+		}
+		return c.Status(http.StatusOK).JSON(schema, MIMESchemaJSON)
+	}
+}
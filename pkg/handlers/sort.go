@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/repositories"
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+// SetSortable sets the fields, by JSON name, clients can sort listings by
+func (h *Handler[T]) SetSortable(jsonNames ...string) error {
+	sortable, err := resolveFields[T](h.Name(), jsonNames)
+	if err != nil {
+		return err
+	}
+	h.sortable = sortable
+	return nil
+}
+
+// parseSort reads ?sort=-start,name from the request, a leading "-" sorts
+// in descending order
+func (h *Handler[T]) parseSort(c *fiber.Ctx) ([]repositories.Sort, error) {
+	sorts := []repositories.Sort{}
+	raw := c.Query("sort")
+	if raw == "" {
+		return sorts, nil
+	}
+
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		desc := strings.HasPrefix(name, "-")
+		name = strings.TrimPrefix(strings.TrimPrefix(name, "-"), "+")
+		field, ok := h.sortable[name]
+		if !ok {
+			return nil, fmt.Errorf("field %s is not sortable", name)
+		}
+		sorts = append(sorts, repositories.Sort{Column: field.Column, Desc: desc})
+	}
+	return sorts, nil
+}
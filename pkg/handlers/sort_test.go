@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/model"
+	"github.com/arturoeanton/go-struc2fiber/pkg/repositories"
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+// sortOf runs parseSort on a request with the given query string
+func sortOf(t *testing.T, h *Handler[model.Skill], query string) ([]repositories.Sort, error) {
+	t.Helper()
+	var sorts []repositories.Sort
+	var err error
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		sorts, err = h.parseSort(c)
+		return nil
+	})
+	if _, testErr := app.Test(httptest.NewRequest("GET", "/?"+query, nil)); testErr != nil {
+		t.Fatal(testErr)
+	}
+	return sorts, err
+}
+
+func TestParseSort(t *testing.T) {
+	h := &Handler[model.Skill]{name: "skill"}
+	if err := h.SetSortable("name", "value"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  []repositories.Sort
+	}{
+		{"", []repositories.Sort{}},
+		{"sort=name", []repositories.Sort{{Column: "name"}}},
+		{"sort=%2Bname", []repositories.Sort{{Column: "name"}}},
+		{"sort=-value", []repositories.Sort{{Column: "value", Desc: true}}},
+		{"sort=-value,name", []repositories.Sort{{Column: "value", Desc: true}, {Column: "name"}}},
+		{"sort=name,%20-value", []repositories.Sort{{Column: "name"}, {Column: "value", Desc: true}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := sortOf(t, h, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSortInvalid(t *testing.T) {
+	h := &Handler[model.Skill]{name: "skill"}
+	if err := h.SetSortable("name"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"sort=value", "field value is not sortable"},
+		{"sort=name,-user_id", "field user_id is not sortable"},
+		{"sort=name,", "field  is not sortable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := sortOf(t, h, tt.query)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	if err := h.SetSortable("unknown"); err == nil {
+		t.Fatal("SetSortable accepted an unknown field")
+	}
+}
+
+func TestGetAllSorted(t *testing.T) {
+	openTestDB(t,
+		&model.Skill{UserID: 1, Name: "go", Value: 30},
+		&model.Skill{UserID: 1, Name: "rust", Value: 10},
+		&model.Skill{UserID: 1, Name: "c", Value: 30},
+		&model.Skill{UserID: 1, Name: "zig", Value: 20},
+	)
+	h := newSkillHandler()
+	if err := h.SetSortable("name", "value"); err != nil {
+		t.Fatal(err)
+	}
+	app := fiber.New()
+	app.Get("/skill", h.GetAll)
+
+	tests := []struct {
+		query string
+		want  []int64
+	}{
+		{"", []int64{1, 2, 3, 4}},
+		{"sort=name", []int64{3, 1, 2, 4}},
+		{"sort=-name", []int64{4, 2, 1, 3}},
+		// Ties are broken by primary key
+		{"sort=-value", []int64{1, 3, 4, 2}},
+		{"sort=-value,name", []int64{3, 1, 4, 2}},
+		{"sort=value&per_page=2&page=2", []int64{1, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			resp, body := send(t, app, "GET", "/skill?"+tt.query, "")
+			if resp.StatusCode != fiber.StatusOK {
+				t.Fatalf("status = %d: %s", resp.StatusCode, body)
+			}
+			ids := responseIDs(t, body)
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Fatalf("ids = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+
+	for _, query := range []string{"sort=user_id", "sort=name&cursor="} {
+		if resp, body := send(t, app, "GET", "/skill?"+query, ""); resp.StatusCode != fiber.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400: %s", query, resp.StatusCode, body)
+		}
+	}
+}
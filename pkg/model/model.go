@@ -12,9 +12,9 @@ type InternalUser struct {
 	Author              *string   `json:"author,omitempty" gorm:"column:author"`
 	LastUpdate          *string   `json:"last_update,omitempty" gorm:"column:last_update"`
 	LastUpdateBy        *string   `json:"last_update_by,omitempty" gorm:"column:last_update_by"`
-	Username            string    `json:"username" gorm:"column:username"  `
+	Username            string    `json:"username" gorm:"column:username" validate:"required,min=3,max=20,pattern=^[a-zA-Z0-9_]+$,unique"`
 	Password            string    `json:"password" gorm:"column:user_password"`
-	Email               string    `json:"email" gorm:"column:email"`
+	Email               string    `json:"email" gorm:"column:email" validate:"required,email,unique"`
 	Phone               string    `json:"phone" gorm:"column:phone"`
 	Role                string    `json:"role" gorm:"column:user_role"`
 	ApiKey              string    `json:"api_key" gorm:"column:api_key"`
@@ -41,9 +41,9 @@ func (InternalUser) TableName() string {
 
 type Skill struct {
 	ID     int64  `json:"id,omitempty"  gorm:"primaryKey"` // Agrega el campo ID
-	UserID int64  `json:"user_id,omitempty" gorm:"column:user_id"`
-	Name   string `json:"name,omitempty" gorm:"column:name"`
-	Value  int    `json:"value,omitempty" gorm:"column:value"`
+	UserID int64  `json:"user_id,omitempty" gorm:"column:user_id" validate:"exists=internal_user"`
+	Name   string `json:"name,omitempty" gorm:"column:name" validate:"required,min=3,max=20,pattern=^[a-zA-Z0-9_]+$"`
+	Value  int    `json:"value,omitempty" gorm:"column:value" validate:"required,min=0.01,max=100"`
 }
 
 func (Skill) TableName() string {
@@ -64,10 +64,10 @@ func (Connect) TableName() string {
 
 type DayOff struct {
 	ID          int64  `json:"id,omitempty"  gorm:"primaryKey"` // Agrega el campo ID
-	UserID      int64  `json:"user_id,omitempty" gorm:"column:user_id"`
-	Start       string `json:"start,omitempty" gorm:"column:start"`
-	End         string `json:"end,omitempty" gorm:"column:end"`
-	Description string `json:"description,omitempty" gorm:"column:description"`
+	UserID      int64  `json:"user_id,omitempty" gorm:"column:user_id" validate:"required,min=1,exists=internal_user"`
+	Start       string `json:"start,omitempty" gorm:"column:start" validate:"required,date"`
+	End         string `json:"end,omitempty" gorm:"column:end" validate:"required,date"`
+	Description string `json:"description,omitempty" gorm:"column:description" validate:"max=200"`
 }
 
 func (DayOff) TableName() string {
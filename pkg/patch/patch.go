@@ -0,0 +1,342 @@
+// Package patch applies RFC 7386 JSON merge patches and RFC 6902 JSON
+// patches to documents decoded with Decode
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrInvalid reports a malformed patch
+	ErrInvalid = errors.New("invalid patch")
+	// ErrConflict reports a patch that cannot be applied to the document,
+	// like a missing path or a failed test operation
+	ErrConflict = errors.New("patch conflict")
+)
+
+// Decode decodes a JSON document keeping numbers as json.Number, so they are
+// encoded back unchanged
+func Decode(data []byte) (interface{}, error) {
+	var doc interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("unexpected data after JSON document")
+	}
+	return doc, nil
+}
+
+// Merge applies an RFC 7386 merge patch to doc: objects are merged
+// recursively, null removes a member and any other value replaces the target
+func Merge(doc, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	target, ok := doc.(map[string]interface{})
+	if !ok {
+		target = map[string]interface{}{}
+	}
+	for key, value := range p {
+		if value == nil {
+			delete(target, key)
+			continue
+		}
+		target[key] = Merge(target[key], value)
+	}
+	return target
+}
+
+// Operation is a single RFC 6902 operation
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+
+	value interface{}
+}
+
+// ParseOperations decodes and checks an RFC 6902 operation list
+func ParseOperations(data []byte) ([]Operation, error) {
+	var ops []Operation
+	if err := json.Unmarshal(data, &ops); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	for i := range ops {
+		op := &ops[i]
+		switch op.Op {
+		case "add", "replace", "test":
+			if op.Value == nil {
+				return nil, fmt.Errorf("%w: operation %d (%s) needs a value", ErrInvalid, i, op.Op)
+			}
+			value, err := Decode(op.Value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: operation %d: %v", ErrInvalid, i, err)
+			}
+			op.value = value
+		case "remove":
+		case "move", "copy":
+			if _, err := parsePointer(op.From); err != nil {
+				return nil, fmt.Errorf("%w: operation %d: %v", ErrInvalid, i, err)
+			}
+			if op.Op == "move" && strings.HasPrefix(op.Path, op.From+"/") {
+				return nil, fmt.Errorf("%w: operation %d moves %s into itself", ErrInvalid, i, op.From)
+			}
+		default:
+			return nil, fmt.Errorf("%w: operation %d has unknown op %q", ErrInvalid, i, op.Op)
+		}
+		if _, err := parsePointer(op.Path); err != nil {
+			return nil, fmt.Errorf("%w: operation %d: %v", ErrInvalid, i, err)
+		}
+	}
+	return ops, nil
+}
+
+// Apply applies the operations to doc in order. doc is modified in place,
+// use the returned document as the root may be replaced.
+func Apply(doc interface{}, ops []Operation) (interface{}, error) {
+	var err error
+	for i, op := range ops {
+		if doc, err = apply(doc, op); err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return doc, nil
+}
+
+func apply(doc interface{}, op Operation) (interface{}, error) {
+	path, _ := parsePointer(op.Path)
+	switch op.Op {
+	case "add":
+		return add(doc, path, copyValue(op.value))
+	case "remove":
+		doc, _, err := remove(doc, path)
+		return doc, err
+	case "replace":
+		if _, err := get(doc, path); err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return copyValue(op.value), nil
+		}
+		return walk(doc, path, func(parent interface{}, key string) (interface{}, error) {
+			switch node := parent.(type) {
+			case map[string]interface{}:
+				node[key] = copyValue(op.value)
+			case []interface{}:
+				i, _ := index(key, len(node))
+				node[i] = copyValue(op.value)
+			}
+			return parent, nil
+		})
+	case "move":
+		if op.From == op.Path {
+			_, err := get(doc, path)
+			return doc, err
+		}
+		from, _ := parsePointer(op.From)
+		doc, value, err := remove(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	case "copy":
+		from, _ := parsePointer(op.From)
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, copyValue(value))
+	case "test":
+		value, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(value, op.value) {
+			return nil, fmt.Errorf("%w: test failed", ErrConflict)
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("%w: unknown op %q", ErrInvalid, op.Op)
+}
+
+// add inserts value at path, shifting array elements when needed
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return walk(doc, path, func(parent interface{}, key string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			node[key] = value
+			return node, nil
+		case []interface{}:
+			i := len(node)
+			if key != "-" {
+				var err error
+				if i, err = index(key, len(node)+1); err != nil {
+					return nil, err
+				}
+			}
+			node = append(node, nil)
+			copy(node[i+1:], node[i:])
+			node[i] = value
+			return node, nil
+		}
+		return nil, fmt.Errorf("%w: cannot add to a %T", ErrConflict, parent)
+	})
+}
+
+// remove deletes the value at path, returning it along with the new document
+func remove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("%w: cannot remove the whole document", ErrConflict)
+	}
+	var removed interface{}
+	doc, err := walk(doc, path, func(parent interface{}, key string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			value, ok := node[key]
+			if !ok {
+				return nil, fmt.Errorf("%w: path not found", ErrConflict)
+			}
+			removed = value
+			delete(node, key)
+			return node, nil
+		case []interface{}:
+			i, err := index(key, len(node))
+			if err != nil {
+				return nil, err
+			}
+			removed = node[i]
+			return append(node[:i], node[i+1:]...), nil
+		}
+		return nil, fmt.Errorf("%w: cannot remove from a %T", ErrConflict, parent)
+	})
+	return doc, removed, err
+}
+
+// get returns the value at path
+func get(doc interface{}, path []string) (interface{}, error) {
+	for _, key := range path {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			value, ok := node[key]
+			if !ok {
+				return nil, fmt.Errorf("%w: path not found", ErrConflict)
+			}
+			doc = value
+		case []interface{}:
+			i, err := index(key, len(node))
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, fmt.Errorf("%w: path not found", ErrConflict)
+		}
+	}
+	return doc, nil
+}
+
+// walk follows path up to the container of its last token and replaces that
+// container with the result of fn
+func walk(doc interface{}, path []string, fn func(parent interface{}, key string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+	key := path[0]
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		child, ok := node[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: path not found", ErrConflict)
+		}
+		child, err := walk(child, path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		node[key] = child
+		return node, nil
+	case []interface{}:
+		i, err := index(key, len(node))
+		if err != nil {
+			return nil, err
+		}
+		child, err := walk(node[i], path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		node[i] = child
+		return node, nil
+	}
+	return nil, fmt.Errorf("%w: path not found", ErrConflict)
+}
+
+// copyValue deep copies a decoded value
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[key] = copyValue(item)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, item := range v {
+			s[i] = copyValue(item)
+		}
+		return s
+	}
+	return value
+}
+
+// equal compares decoded values, numbers by value
+func equal(a, b interface{}) bool {
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for key, value := range x {
+			other, ok := y[key]
+			if !ok || !equal(value, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		if x == y {
+			return true
+		}
+		fx, errX := x.Float64()
+		fy, errY := y.Float64()
+		return errX == nil && errY == nil && fx == fy
+	}
+	return a == b
+}
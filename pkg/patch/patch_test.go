@@ -0,0 +1,216 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// normalize decodes and encodes back a JSON document, sorting its keys
+func normalize(t *testing.T, data string) string {
+	t.Helper()
+	doc, err := Decode([]byte(data))
+	if err != nil {
+		t.Fatalf("decoding %s: %v", data, err)
+	}
+	out, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+// applyPatch parses and applies a JSON patch to a JSON document
+func applyPatch(t *testing.T, doc, patch string) (string, error) {
+	t.Helper()
+	ops, err := ParseOperations([]byte(patch))
+	if err != nil {
+		return "", err
+	}
+	value, err := Decode([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, err = Apply(value, ops); err != nil {
+		return "", err
+	}
+	out, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out), nil
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+		err   error
+	}{
+		// RFC 6902 appendix A
+		{"A.1 add object member", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`, nil},
+		{"A.2 add array element", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`, nil},
+		{"A.3 remove object member", `{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`, nil},
+		{"A.4 remove array element", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`, nil},
+		{"A.5 replace", `{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`, nil},
+		{"A.6 move value", `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`, nil},
+		{"A.7 move array element", `{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`, nil},
+		{"A.8 test", `{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`,
+			`{"baz":"qux","foo":["a",2,"c"]}`, nil},
+		{"A.9 test failure", `{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`, "", ErrConflict},
+		{"A.10 add nested member", `{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"child":{"grandchild":{}},"foo":"bar"}`, nil},
+		{"A.11 unrecognized elements", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux","xyz":123}]`, `{"baz":"qux","foo":"bar"}`, nil},
+		{"A.12 add to missing target", `{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, "", ErrConflict},
+		{"A.14 escape ordering", `{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10}]`, `{"/":9,"~1":10}`, nil},
+		{"A.15 strings are not numbers", `{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":"10"}]`, "", ErrConflict},
+		{"A.16 add array value", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`, nil},
+
+		// Pointer escaping
+		{"escaped slash", `{}`, `[{"op":"add","path":"/a~1b","value":1}]`, `{"a/b":1}`, nil},
+		{"escaped tilde", `{"m~n":1}`, `[{"op":"replace","path":"/m~0n","value":2}]`, `{"m~n":2}`, nil},
+		{"empty key", `{"":1}`, `[{"op":"remove","path":"/"}]`, `{}`, nil},
+		{"relative pointer", `{}`, `[{"op":"add","path":"a","value":1}]`, "", ErrInvalid},
+
+		// Array indexes
+		{"add at the end", `{"a":[1,2]}`, `[{"op":"add","path":"/a/2","value":3}]`, `{"a":[1,2,3]}`, nil},
+		{"append", `{"a":[]}`, `[{"op":"add","path":"/a/-","value":1},{"op":"add","path":"/a/-","value":2}]`, `{"a":[1,2]}`, nil},
+		{"add past the end", `{"a":[1,2]}`, `[{"op":"add","path":"/a/3","value":3}]`, "", ErrConflict},
+		{"remove past the end", `{"a":[1,2]}`, `[{"op":"remove","path":"/a/2"}]`, "", ErrConflict},
+		{"replace past the end", `{"a":[1,2]}`, `[{"op":"replace","path":"/a/2","value":3}]`, "", ErrConflict},
+		{"remove dash", `{"a":[1,2]}`, `[{"op":"remove","path":"/a/-"}]`, "", ErrConflict},
+		{"leading zero", `{"a":[1,2]}`, `[{"op":"replace","path":"/a/01","value":3}]`, "", ErrConflict},
+		{"negative index", `{"a":[1,2]}`, `[{"op":"remove","path":"/a/-1"}]`, "", ErrConflict},
+
+		// Move and copy
+		{"move into own child", `{"a":{"b":1}}`, `[{"op":"move","from":"/a","path":"/a/c"}]`, "", ErrInvalid},
+		{"move to itself", `{"a":{"b":1}}`, `[{"op":"move","from":"/a","path":"/a"}]`, `{"a":{"b":1}}`, nil},
+		{"move missing", `{"a":1}`, `[{"op":"move","from":"/b","path":"/c"}]`, "", ErrConflict},
+		{"copy", `{"a":{"b":[1]}}`, `[{"op":"copy","from":"/a","path":"/c"},{"op":"add","path":"/c/b/-","value":2}]`, `{"a":{"b":[1]},"c":{"b":[1,2]}}`, nil},
+		{"copy array element", `{"a":[1,2]}`, `[{"op":"copy","from":"/a/1","path":"/a/0"}]`, `{"a":[2,1,2]}`, nil},
+
+		// Test on nested values
+		{"test object", `{"a":{"b":[1,{"c":2}]}}`, `[{"op":"test","path":"/a","value":{"b":[1,{"c":2.0}]}}]`, `{"a":{"b":[1,{"c":2}]}}`, nil},
+		{"test object mismatch", `{"a":{"b":[1,{"c":2}]}}`, `[{"op":"test","path":"/a","value":{"b":[1,{"c":2,"d":3}]}}]`, "", ErrConflict},
+		{"test array order", `{"a":[1,2]}`, `[{"op":"test","path":"/a","value":[2,1]}]`, "", ErrConflict},
+		{"test null", `{"a":null}`, `[{"op":"test","path":"/a","value":null}]`, `{"a":null}`, nil},
+		{"test missing", `{}`, `[{"op":"test","path":"/a","value":null}]`, "", ErrConflict},
+
+		// Whole document and malformed patches
+		{"replace root", `{"a":1}`, `[{"op":"replace","path":"","value":[1]}]`, `[1]`, nil},
+		{"remove root", `{"a":1}`, `[{"op":"remove","path":""}]`, "", ErrConflict},
+		{"replace missing", `{"a":1}`, `[{"op":"replace","path":"/b","value":1}]`, "", ErrConflict},
+		{"missing value", `{}`, `[{"op":"add","path":"/a"}]`, "", ErrInvalid},
+		{"unknown op", `{}`, `[{"op":"merge","path":"/a","value":1}]`, "", ErrInvalid},
+		{"not a list", `{}`, `{"op":"add","path":"/a","value":1}`, "", ErrInvalid},
+		// Operations apply in order, a failure rejects the whole patch
+		{"failure after changes", `{"a":1}`, `[{"op":"remove","path":"/a"},{"op":"test","path":"/a","value":1}]`, "", ErrConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyPatch(t, tt.doc, tt.patch)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := normalize(t, tt.want); got != want {
+				t.Fatalf("got %s, want %s", got, want)
+			}
+		})
+	}
+
+	// RFC 6902 A.13: duplicated members make an invalid patch, the last one
+	// wins when decoding so the remove of a missing path fails
+	if _, err := applyPatch(t, `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux","op":"remove"}]`); err == nil {
+		t.Error("A.13: invalid patch applied")
+	}
+}
+
+func TestApplyCopiesValues(t *testing.T) {
+	doc, _ := Decode([]byte(`{"a":[1]}`))
+	ops, err := ParseOperations([]byte(`[{"op":"add","path":"/b","value":{"c":1}},{"op":"add","path":"/d","value":{"c":1}}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err = Apply(doc, ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := doc.(map[string]interface{})
+	obj["b"].(map[string]interface{})["c"] = 2
+	if obj["d"].(map[string]interface{})["c"] != json.Number("1") {
+		t.Fatal("values added by different operations are shared")
+	}
+	if ops[0].value.(map[string]interface{})["c"] != json.Number("1") {
+		t.Fatal("changing the document changed the patch")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	// RFC 7386 appendix A
+	tests := []struct {
+		doc   string
+		patch string
+		want  string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		// Deleting a member missing in the target is not an error
+		{`{"a":1}`, `{"b":null}`, `{"a":1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.doc+" "+tt.patch, func(t *testing.T) {
+			doc, err := Decode([]byte(tt.doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			patch, err := Decode([]byte(tt.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := json.Marshal(Merge(doc, patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := normalize(t, tt.want); string(out) != want {
+				t.Fatalf("got %s, want %s", out, want)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	doc, err := Decode([]byte(`{"big":12345678901234567890,"pi":3.14}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, _ := json.Marshal(doc)
+	if string(out) != `{"big":12345678901234567890,"pi":3.14}` {
+		t.Fatalf("numbers changed: %s", out)
+	}
+	for _, data := range []string{`{"a":1} {"b":2}`, `{"a":`, ``} {
+		if _, err := Decode([]byte(data)); err == nil {
+			t.Errorf("Decode(%q) accepted", data)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package patch
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parsePointer splits an RFC 6901 JSON pointer into its unescaped tokens, the
+// empty pointer refers to the whole document
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("pointer %q must start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+// index parses an array index lower than size
+func index(token string, size int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (len(token) > 1 && token[0] == '0') || token[0] == '+' {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrConflict, token)
+	}
+	if i >= size {
+		return 0, fmt.Errorf("%w: array index %d out of range", ErrConflict, i)
+	}
+	return i, nil
+}
@@ -0,0 +1,210 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Association describes a has one or has many association written along
+// with its owner, see SetAssociations
+type Association struct {
+	// Name is the Go field name of the association
+	Name string
+	// JSONName is the name of the association in bodies
+	JSONName string
+	// ForeignKey is the JSON name of the field of the children referencing the owner
+	ForeignKey string
+	// Model is the struct type of the children
+	Model reflect.Type
+}
+
+// Associations resolves the has one and has many associations of T by JSON name
+func Associations[T any](jsonNames ...string) ([]*Association, error) {
+	s, err := ParseSchema(CreateNewElement[T]())
+	if err != nil {
+		return nil, err
+	}
+
+	associations := []*Association{}
+	for _, name := range jsonNames {
+		var found *schema.Relationship
+		for fieldName, rel := range s.Relationships.Relations {
+			if jsonName(s.FieldsByName[fieldName]) == name {
+				found = rel
+				break
+			}
+		}
+		if found == nil || (found.Type != schema.HasOne && found.Type != schema.HasMany) {
+			return nil, fmt.Errorf("%s has no has one or has many association %s", s.Name, name)
+		}
+		a := &Association{Name: found.Name, JSONName: name, Model: found.FieldSchema.ModelType}
+		for _, ref := range found.References {
+			if ref.OwnPrimaryKey && ref.PrimaryKey != nil {
+				a.ForeignKey = jsonName(ref.ForeignKey)
+				break
+			}
+		}
+		associations = append(associations, a)
+	}
+	return associations, nil
+}
+
+// SetAssociations sets the has one and has many associations, by Go field
+// name, Create and Update write along with the items. The children held by
+// an item replace the stored ones: new children are created, the others
+// updated and the stored children missing are deleted. Associations left nil
+// are untouched. Without SetAssociations no association is written.
+func (r *Repository[T]) SetAssociations(names ...string) error {
+	s, err := ParseSchema(CreateNewElement[T]())
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		rel, ok := s.Relationships.Relations[name]
+		if !ok || (rel.Type != schema.HasOne && rel.Type != schema.HasMany) {
+			return fmt.Errorf("%s has no has one or has many association %s", s.Name, name)
+		}
+		if rel.FieldSchema.PrioritizedPrimaryField == nil {
+			return fmt.Errorf("%s.%s has no single primary key", s.Name, name)
+		}
+	}
+	r.associations = names
+	return nil
+}
+
+// write saves items with fn, along with their associations in the same
+// transaction when some are set
+func (r *Repository[T]) write(ctx context.Context, items []*T, fn func(db *gorm.DB) *gorm.DB) (int64, error) {
+	if len(r.associations) == 0 {
+		result := fn(r.conn(ctx).Omit(clause.Associations))
+		return result.RowsAffected, translateError(result.Error)
+	}
+
+	var rowsAffected int64
+	err := transaction(ctx, r.conn(ctx), func(ctx context.Context, tx *gorm.DB) error {
+		result := fn(tx.Omit(clause.Associations))
+		if result.Error != nil {
+			return result.Error
+		}
+		rowsAffected = result.RowsAffected
+		for _, item := range items {
+			if err := r.saveAssociations(ctx, tx, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return rowsAffected, err
+}
+
+// saveAssociations replaces the stored children of item by the ones it holds
+func (r *Repository[T]) saveAssociations(ctx context.Context, tx *gorm.DB, item *T) error {
+	s, err := ParseSchema(item)
+	if err != nil {
+		return err
+	}
+	owner := reflect.ValueOf(item).Elem()
+	for _, name := range r.associations {
+		rel := s.Relationships.Relations[name]
+		field := owner.FieldByIndex(rel.Field.StructField.Index)
+
+		children := []reflect.Value{}
+		switch field.Kind() {
+		case reflect.Slice:
+			if field.IsNil() {
+				continue
+			}
+			for i := 0; i < field.Len(); i++ {
+				child := field.Index(i)
+				if child.Kind() != reflect.Ptr {
+					child = child.Addr()
+				} else if child.IsNil() {
+					continue
+				}
+				children = append(children, child)
+			}
+		case reflect.Ptr:
+			if field.IsNil() {
+				continue
+			}
+			children = append(children, field)
+		default:
+			if field.IsZero() {
+				continue
+			}
+			children = append(children, field.Addr())
+		}
+
+		if err := replaceChildren(ctx, tx, owner, rel, children); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceChildren makes children, pointers to structs, the only children of
+// owner in rel. Children with a primary key must already belong to owner.
+func replaceChildren(ctx context.Context, tx *gorm.DB, owner reflect.Value, rel *schema.Relationship, children []reflect.Value) error {
+	pk := rel.FieldSchema.PrioritizedPrimaryField
+
+	// The foreign key values the children of owner hold
+	keys := map[*schema.Field]interface{}{}
+	for _, ref := range rel.References {
+		var value interface{} = ref.PrimaryValue
+		if ref.PrimaryKey != nil {
+			value, _ = ref.PrimaryKey.ValueOf(ctx, owner)
+		}
+		keys[ref.ForeignKey] = value
+	}
+	stored := func() *gorm.DB {
+		db := tx.Model(reflect.New(rel.FieldSchema.ModelType).Interface())
+		for field, value := range keys {
+			db = db.Where(clause.Eq{Column: clause.Column{Name: field.DBName}, Value: value})
+		}
+		return db
+	}
+
+	ids := reflect.New(reflect.SliceOf(pk.FieldType))
+	if err := stored().Pluck(pk.DBName, ids.Interface()).Error; err != nil {
+		return err
+	}
+	owned := map[interface{}]bool{}
+	for i := 0; i < ids.Elem().Len(); i++ {
+		owned[ids.Elem().Index(i).Interface()] = true
+	}
+
+	kept := []interface{}{}
+	for _, child := range children {
+		for field, value := range keys {
+			if err := field.Set(ctx, child.Elem(), value); err != nil {
+				return err
+			}
+		}
+		id, zero := pk.ValueOf(ctx, child.Elem())
+		db := tx.Omit(clause.Associations)
+		switch {
+		case zero:
+			db = db.Create(child.Interface())
+		case owned[id]:
+			db = db.Save(child.Interface())
+		default:
+			return &Error{Kind: KindBadInput, Message: fmt.Sprintf("%s %v does not belong to the item", jsonName(rel.Field), id)}
+		}
+		if db.Error != nil {
+			return db.Error
+		}
+		id, _ = pk.ValueOf(ctx, child.Elem())
+		kept = append(kept, id)
+	}
+
+	remove := stored()
+	if len(kept) > 0 {
+		remove = remove.Where(clause.Not(clause.IN{Column: clause.Column{Name: pk.DBName}, Values: kept}))
+	}
+	return remove.Delete(reflect.New(rel.FieldSchema.ModelType).Interface()).Error
+}
@@ -0,0 +1,137 @@
+package repositories
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// crate is the owner of parts in the association tests
+type crate struct {
+	ID    int64  `json:"id" gorm:"primaryKey"`
+	Label string `json:"label" gorm:"column:label"`
+	Parts []part `json:"parts" gorm:"foreignKey:CrateID"`
+}
+
+// part is a child of a crate
+type part struct {
+	ID      int64  `json:"id" gorm:"primaryKey"`
+	CrateID int64  `json:"crate_id" gorm:"column:crate_id"`
+	Name    string `json:"name" gorm:"column:name"`
+}
+
+// openCrateDB points DB to a new in-memory database holding crate 1 with
+// parts a and b, and crate 2 with part c
+func openCrateDB(t *testing.T) {
+	t.Helper()
+	openTestDB(t)
+	if err := DB.AutoMigrate(&crate{}, &part{}); err != nil {
+		t.Fatal(err)
+	}
+	crates := []crate{
+		{Label: "one", Parts: []part{{Name: "a"}, {Name: "b"}}},
+		{Label: "two", Parts: []part{{Name: "c"}}},
+	}
+	if err := DB.Create(&crates).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// storedParts returns the parts stored, by ID
+func storedParts(t *testing.T) []part {
+	t.Helper()
+	parts := []part{}
+	if err := DB.Order("id").Find(&parts).Error; err != nil {
+		t.Fatal(err)
+	}
+	return parts
+}
+
+func TestAssociations(t *testing.T) {
+	tests := []struct {
+		name  string
+		parts []part
+		err   error
+		want  []part
+	}{
+		// The stored parts are updated, the new ones created
+		{"upsert", []part{{ID: 1, Name: "a2"}, {ID: 2, Name: "b"}, {Name: "d"}}, nil, []part{
+			{1, 1, "a2"}, {2, 1, "b"}, {3, 2, "c"}, {4, 1, "d"},
+		}},
+		{"omitted deleted", []part{{ID: 2, Name: "b"}}, nil, []part{
+			{2, 1, "b"}, {3, 2, "c"},
+		}},
+		{"empty clears", []part{}, nil, []part{
+			{3, 2, "c"},
+		}},
+		{"nil untouched", nil, nil, []part{
+			{1, 1, "a"}, {2, 1, "b"}, {3, 2, "c"},
+		}},
+		// Nothing is written when a part of another crate is sent
+		{"other owner", []part{{ID: 1, Name: "a2"}, {ID: 3, Name: "c2"}}, ErrBadInput, []part{
+			{1, 1, "a"}, {2, 1, "b"}, {3, 2, "c"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			openCrateDB(t)
+			repo := NewRepository[crate]()
+			if err := repo.SetAssociations("Parts"); err != nil {
+				t.Fatal(err)
+			}
+			_, err := repo.Update(&crate{ID: 1, Label: "uno", Parts: tt.parts})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if got := storedParts(t); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("parts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssociationsOnCreate(t *testing.T) {
+	openCrateDB(t)
+	repo := NewRepository[crate]()
+	if err := repo.SetAssociations("Parts"); err != nil {
+		t.Fatal(err)
+	}
+	id, err := repo.Create(&crate{Label: "three", Parts: []part{{Name: "d"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []part{{1, 1, "a"}, {2, 1, "b"}, {3, 2, "c"}, {4, *id, "d"}}
+	if got := storedParts(t); !reflect.DeepEqual(got, want) {
+		t.Fatalf("parts = %v, want %v", got, want)
+	}
+
+	// Parts of another crate cannot be moved to a new one
+	_, err = repo.Create(&crate{Label: "four", Parts: []part{{ID: 1, Name: "a"}}})
+	if !errors.Is(err, ErrBadInput) {
+		t.Fatalf("err = %v, want ErrBadInput", err)
+	}
+	var count int64
+	if err := DB.Model(&crate{}).Where("label = ?", "four").Count(&count).Error; err != nil || count != 0 {
+		t.Fatalf("crates four = %d, %v, want the create rolled back", count, err)
+	}
+}
+
+func TestWithoutAssociations(t *testing.T) {
+	openCrateDB(t)
+	repo := NewRepository[crate]()
+
+	// Without SetAssociations the children sent are ignored
+	if _, err := repo.Create(&crate{Label: "three", Parts: []part{{Name: "d"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Update(&crate{ID: 1, Label: "uno", Parts: []part{{ID: 1, Name: "a2"}, {Name: "e"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CreateInBatches([]*crate{{Label: "four", Parts: []part{{Name: "f"}}}}, 10); err != nil {
+		t.Fatal(err)
+	}
+	want := []part{{1, 1, "a"}, {2, 1, "b"}, {3, 2, "c"}}
+	if got := storedParts(t); !reflect.DeepEqual(got, want) {
+		t.Fatalf("parts = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCanceledContext(t *testing.T) {
+	openTestDB(t, widget{Name: "gear", Rank: 1}, widget{Name: "bolt", Rank: 2})
+	repo := NewRepository[widget]()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"GetAll", func() error { _, _, err := repo.GetAllContext(ctx); return err }},
+		{"GetAllByQuery", func() error { _, _, err := repo.GetAllByQueryContext(ctx, &Query{Limit: 1}); return err }},
+		{"GetAllByCursor", func() error { _, _, err := repo.GetAllByCursorContext(ctx, &Query{Limit: 1}); return err }},
+		{"GetByID", func() error { _, err := repo.GetByIDContext(ctx, 1); return err }},
+		{"GetByIDWithQuery", func() error { _, err := repo.GetByIDWithQueryContext(ctx, 1, &Query{}); return err }},
+		{"GetByCriteria", func() error { _, _, err := repo.GetByCriteriaContext(ctx, "rank > ?", 0); return err }},
+		{"GetByIDForUpdate", func() error { _, err := repo.GetByIDForUpdateContext(ctx, 1); return err }},
+		{"Create", func() error { _, err := repo.CreateContext(ctx, &widget{Name: "nut"}); return err }},
+		{"CreateInBatches", func() error {
+			_, err := repo.CreateInBatchesContext(ctx, []*widget{{Name: "nut"}, {Name: "pin"}}, 1)
+			return err
+		}},
+		{"Update", func() error { _, err := repo.UpdateContext(ctx, &widget{ID: 1, Name: "cog"}); return err }},
+		{"UpdateColumns", func() error {
+			_, err := repo.UpdateColumnsContext(ctx, &widget{ID: 1, Name: "cog"}, []string{"name"})
+			return err
+		}},
+		{"Delete", func() error { _, err := repo.DeleteContext(ctx, 1); return err }},
+		{"DeleteByIDs", func() error { _, err := repo.DeleteByIDsContext(ctx, []interface{}{1, 2}); return err }},
+		{"Transaction", func() error {
+			return repo.TransactionContext(ctx, func(repo IRepository[widget]) error {
+				_, err := repo.CreateContext(ctx, &widget{Name: "nut"})
+				return err
+			})
+		}},
+		{"WithTx", func() error {
+			return WithTx(ctx, func(ctx context.Context) error {
+				_, err := repo.CreateContext(ctx, &widget{Name: "nut"})
+				return err
+			})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, context.Canceled) {
+				t.Fatalf("err = %v, want context.Canceled", err)
+			}
+		})
+	}
+
+	// Nothing was written, and the repository still works with a live context
+	items, total, err := repo.GetAllContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || items[0].Name != "gear" || items[1].Name != "bolt" {
+		t.Fatalf("items = %v (%d), want gear and bolt unchanged", ids(items), total)
+	}
+}
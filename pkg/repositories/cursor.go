@@ -0,0 +1,182 @@
+package repositories
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrInvalidCursor is returned when a cursor cannot be decoded
+var ErrInvalidCursor = &Error{Kind: KindBadInput, Message: "invalid cursor"}
+
+// Cursors point to the pages around the one returned by GetAllByCursor,
+// an empty cursor means there is no such page
+type Cursors struct {
+	Next string
+	Prev string
+}
+
+// cursor is the decoded form of the opaque cursors handed to clients
+type cursor struct {
+	Value    string `json:"v"`
+	Key      string `json:"k,omitempty"`
+	Backward bool   `json:"b,omitempty"`
+}
+
+func (c *cursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(raw string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	c := &cursor{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// GetAllByCursor returns up to q.Limit items following (or preceding) q.Cursor,
+// ordered by q.CursorColumn and then by primary key. An empty cursor starts at
+// the beginning of the table. Since pages are located by value instead of
+// offset, rows inserted while walking the table never shift the pages.
+func (r *Repository[T]) GetAllByCursor(q *Query) ([]*T, *Cursors, error) {
+	return r.GetAllByCursorContext(r.ctx, q)
+}
+
+// GetAllByCursorContext is GetAllByCursor bound to ctx
+func (r *Repository[T]) GetAllByCursorContext(ctx context.Context, q *Query) ([]*T, *Cursors, error) {
+	items := []*T{}
+	s, err := ParseSchema(CreateNewElement[T]())
+	if err != nil {
+		return items, nil, err
+	}
+	pk := s.PrioritizedPrimaryField
+	if pk == nil {
+		return items, nil, fmt.Errorf("%s has no primary key", s.Name)
+	}
+	sortField := pk
+	if q.CursorColumn != "" {
+		if sortField = s.LookUpField(q.CursorColumn); sortField == nil {
+			return items, nil, NewError(KindBadInput, fmt.Errorf("unknown cursor column %s", q.CursorColumn))
+		}
+	}
+	withKey := sortField != pk
+	if len(q.Sorts) > 0 {
+		return items, nil, NewError(KindBadInput, fmt.Errorf("cursor listings are always ordered by %s", sortField.DBName))
+	}
+
+	current := &cursor{}
+	if q.Cursor != "" {
+		if current, err = decodeCursor(q.Cursor); err != nil {
+			return items, nil, err
+		}
+	}
+
+	db, err := applyFilters(r.withQueryPreloads(r.conn(ctx), q, sortField.DBName), q)
+	if err != nil {
+		return items, nil, err
+	}
+	if q.Cursor != "" {
+		cond, err := keysetCondition(current, sortField, pk, withKey)
+		if err != nil {
+			return items, nil, err
+		}
+		db = db.Where(cond)
+	}
+	db = db.Order(clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: sortField.DBName}, Desc: current.Backward})
+	if withKey {
+		db = db.Order(clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: pk.DBName}, Desc: current.Backward})
+	}
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	// Fetch one extra row to know whether there is another page
+	if err := db.Limit(limit + 1).Find(&items).Error; err != nil {
+		return items, nil, translateError(err)
+	}
+	more := len(items) > limit
+	if more {
+		items = items[:limit]
+	}
+	if current.Backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	cursors := &Cursors{}
+	if len(items) == 0 {
+		return items, cursors, nil
+	}
+	if more || current.Backward {
+		cursors.Next = cursorAt(items[len(items)-1], sortField, pk, withKey, false)
+	}
+	if (more && current.Backward) || (!current.Backward && q.Cursor != "") {
+		cursors.Prev = cursorAt(items[0], sortField, pk, withKey, true)
+	}
+	return items, cursors, nil
+}
+
+// keysetCondition selects the rows placed after (or before) the cursor
+func keysetCondition(c *cursor, sortField, pk *schema.Field, withKey bool) (clause.Expression, error) {
+	value, err := ParseValue(sortField.FieldType, c.Value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	column := clause.Column{Table: clause.CurrentTable, Name: sortField.DBName}
+	beyond := func(col clause.Column, v interface{}) clause.Expression {
+		if c.Backward {
+			return clause.Lt{Column: col, Value: v}
+		}
+		return clause.Gt{Column: col, Value: v}
+	}
+	if !withKey {
+		return beyond(column, value), nil
+	}
+
+	key, err := ParseValue(pk.FieldType, c.Key)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	pkColumn := clause.Column{Table: clause.CurrentTable, Name: pk.DBName}
+	return clause.Or(
+		beyond(column, value),
+		clause.And(clause.Eq{Column: column, Value: value}, beyond(pkColumn, key)),
+	), nil
+}
+
+// cursorAt builds the cursor pointing right after (or before) item
+func cursorAt(item interface{}, sortField, pk *schema.Field, withKey bool, backward bool) string {
+	v := reflect.ValueOf(item).Elem()
+	value, _ := sortField.ValueOf(context.Background(), v)
+	c := &cursor{Value: formatValue(deref(value)), Backward: backward}
+	if withKey {
+		key, _ := pk.ValueOf(context.Background(), v)
+		c.Key = formatValue(deref(key))
+	}
+	return c.encode()
+}
+
+// deref returns the value pointed by value when it is a pointer
+func deref(value interface{}) interface{} {
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil
+	}
+	return rv.Interface()
+}
@@ -0,0 +1,159 @@
+package repositories
+
+import (
+	"encoding/base64"
+	"slices"
+	"testing"
+)
+
+// rankedWidgets ordered by rank and then ID are 2, 4, 7, 3, 6, 1, 5
+var rankedWidgets = []widget{
+	{Name: "w1", Rank: 3, Kind: "a"},
+	{Name: "w2", Rank: 1, Kind: "b"},
+	{Name: "w3", Rank: 2, Kind: "a"},
+	{Name: "w4", Rank: 1, Kind: "a"},
+	{Name: "w5", Rank: 3, Kind: "b"},
+	{Name: "w6", Rank: 2, Kind: "b"},
+	{Name: "w7", Rank: 1, Kind: "a"},
+}
+
+// walk follows the cursors of q, next or prev ones, until the end of the
+// listing and returns the IDs of every page
+func walk(t *testing.T, repo *Repository[widget], q Query, backward bool) [][]int64 {
+	t.Helper()
+	pages := [][]int64{}
+	for i := 0; i < 20; i++ {
+		items, cursors, err := repo.GetAllByCursor(&q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, ids(items))
+		next := cursors.Next
+		if backward {
+			next = cursors.Prev
+		}
+		if next == "" {
+			return pages
+		}
+		q.Cursor = next
+	}
+	t.Fatal("the cursors never end")
+	return nil
+}
+
+func TestGetAllByCursorPrimaryKey(t *testing.T) {
+	openTestDB(t, rankedWidgets...)
+	repo := NewRepository[widget]()
+
+	forward := walk(t, repo, Query{Limit: 3}, false)
+	want := [][]int64{{1, 2, 3}, {4, 5, 6}, {7}}
+	if !slices.EqualFunc(forward, want, slices.Equal) {
+		t.Fatalf("forward pages = %v, want %v", forward, want)
+	}
+
+	// Walk back from the cursor preceding the last page
+	_, cursors, err := repo.GetAllByCursor(&Query{Limit: 3, Cursor: (&cursor{Value: "6"}).encode()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	backward := walk(t, repo, Query{Limit: 3, Cursor: cursors.Prev}, true)
+	want = [][]int64{{4, 5, 6}, {1, 2, 3}}
+	if !slices.EqualFunc(backward, want, slices.Equal) {
+		t.Fatalf("backward pages = %v, want %v", backward, want)
+	}
+}
+
+func TestGetAllByCursorTieBreaker(t *testing.T) {
+	openTestDB(t, rankedWidgets...)
+	repo := NewRepository[widget]()
+
+	for _, limit := range []int{1, 2, 3, 7, 10} {
+		q := Query{Limit: limit, CursorColumn: "rank"}
+		got := slices.Concat(walk(t, repo, q, false)...)
+		want := []int64{2, 4, 7, 3, 6, 1, 5}
+		if !slices.Equal(got, want) {
+			t.Fatalf("limit %d: walked %v, want %v", limit, got, want)
+		}
+	}
+}
+
+func TestGetAllByCursorBackward(t *testing.T) {
+	openTestDB(t, rankedWidgets...)
+	repo := NewRepository[widget]()
+
+	// Backward pages are read in descending order and returned in ascending order
+	pages := walk(t, repo, Query{Limit: 2, CursorColumn: "rank"}, false)
+	last := pages[len(pages)-1]
+	if !slices.Equal(last, []int64{5}) {
+		t.Fatalf("last page = %v, want [5]", last)
+	}
+	start := (&cursor{Value: "3", Key: "5", Backward: true}).encode()
+	backward := walk(t, repo, Query{Limit: 2, CursorColumn: "rank", Cursor: start}, true)
+	want := [][]int64{{6, 1}, {7, 3}, {2, 4}}
+	if !slices.EqualFunc(backward, want, slices.Equal) {
+		t.Fatalf("backward pages = %v, want %v", backward, want)
+	}
+
+	_, _, err := repo.GetAllByCursor(&Query{Limit: 2, Sorts: []Sort{{Column: "rank", Desc: true}}})
+	if KindOf(err) != KindBadInput {
+		t.Fatalf("sorting a cursor listing: err = %v, want a bad input error", err)
+	}
+}
+
+func TestGetAllByCursorInvalid(t *testing.T) {
+	openTestDB(t, rankedWidgets...)
+	repo := NewRepository[widget]()
+
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+	tests := []struct {
+		name   string
+		column string
+		cursor string
+	}{
+		{"not base64", "", "@@@"},
+		{"not json", "", encode("not json")},
+		{"value of another type", "", encode(`{"v":"abc"}`)},
+		{"tampered key", "rank", encode(`{"v":"1","k":"x"}`)},
+		{"missing key", "rank", encode(`{"v":"1"}`)},
+		{"value of another type with key", "rank", encode(`{"v":"one","k":"2"}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := repo.GetAllByCursor(&Query{Limit: 2, CursorColumn: tt.column, Cursor: tt.cursor})
+			if err != ErrInvalidCursor {
+				t.Fatalf("err = %v, want %v", err, ErrInvalidCursor)
+			}
+		})
+	}
+
+	_, _, err := repo.GetAllByCursor(&Query{Limit: 2, CursorColumn: "unknown"})
+	if KindOf(err) != KindBadInput {
+		t.Fatalf("unknown cursor column: err = %v, want a bad input error", err)
+	}
+}
+
+func TestGetAllByCursorFilters(t *testing.T) {
+	openTestDB(t, rankedWidgets...)
+	repo := NewRepository[widget]()
+
+	q := Query{Limit: 2, CursorColumn: "rank", Filters: []Filter{{Column: "kind", Operator: Eq, Value: "a"}}}
+	items, cursors, err := repo.GetAllByCursor(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(items); !slices.Equal(got, []int64{4, 7}) {
+		t.Fatalf("first page = %v, want [4 7]", got)
+	}
+
+	// Rows inserted before the cursor do not shift the next pages
+	if err := DB.Create(&widget{Name: "w8", Rank: 0, Kind: "a"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	q.Cursor = cursors.Next
+	got := slices.Concat(walk(t, repo, q, false)...)
+	if !slices.Equal(got, []int64{3, 1}) {
+		t.Fatalf("next pages = %v, want [3 1]", got)
+	}
+}
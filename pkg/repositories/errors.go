@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ErrorKind classifies the errors returned by repositories and services so
+// callers can react to them without looking at driver specific messages
+type ErrorKind int
+
+const (
+	KindInternal ErrorKind = iota
+	KindNotFound
+	KindConflict
+	KindValidation
+	KindConstraint
+	KindBadInput
+)
+
+var kindNames = map[ErrorKind]string{
+	KindInternal:   "internal",
+	KindNotFound:   "not_found",
+	KindConflict:   "conflict",
+	KindValidation: "validation",
+	KindConstraint: "constraint",
+	KindBadInput:   "bad_input",
+}
+
+func (k ErrorKind) String() string {
+	return kindNames[k]
+}
+
+// Error is an error classified by kind
+type Error struct {
+	Kind    ErrorKind
+	Message string
+	Err     error
+}
+
+var (
+	// ErrNotFound matches, with errors.Is, every not found error
+	ErrNotFound = &Error{Kind: KindNotFound, Message: "record not found"}
+	// ErrConflict matches every unique constraint violation
+	ErrConflict = &Error{Kind: KindConflict, Message: "record already exists"}
+	// ErrValidation matches every validation failure
+	ErrValidation = &Error{Kind: KindValidation, Message: "validation failed"}
+	// ErrConstraint matches every foreign key, not null or check constraint violation
+	ErrConstraint = &Error{Kind: KindConstraint, Message: "constraint violated"}
+	// ErrBadInput matches every malformed request error
+	ErrBadInput = &Error{Kind: KindBadInput, Message: "bad input"}
+)
+
+func (e *Error) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return e.Kind.String()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is an *Error of the same kind
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Kind == e.Kind
+}
+
+// NewError returns an error of the given kind wrapping err
+func NewError(kind ErrorKind, err error) *Error {
+	return &Error{Kind: kind, Err: err}
+}
+
+// KindOf returns the kind of err, KindInternal for unclassified errors
+func KindOf(err error) ErrorKind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return KindInternal
+}
+
+// translateError classifies the errors returned by GORM and the database drivers
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+
+	msg := strings.ToLower(err.Error())
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return &Error{Kind: KindNotFound, Message: ErrNotFound.Message, Err: err}
+	case errors.Is(err, gorm.ErrDuplicatedKey),
+		strings.Contains(msg, "unique constraint"),
+		strings.Contains(msg, "duplicate key"),
+		strings.Contains(msg, "duplicate entry"):
+		return &Error{Kind: KindConflict, Message: ErrConflict.Message, Err: err}
+	case errors.Is(err, gorm.ErrForeignKeyViolated),
+		errors.Is(err, gorm.ErrCheckConstraintViolated),
+		strings.Contains(msg, "foreign key constraint"),
+		strings.Contains(msg, "not null constraint"),
+		strings.Contains(msg, "violates not-null constraint"),
+		strings.Contains(msg, "check constraint"):
+		return &Error{Kind: KindConstraint, Message: ErrConstraint.Message, Err: err}
+	}
+	return err
+}
@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestTranslateError(t *testing.T) {
+	openTestDB(t, widget{Name: "gear", Rank: 1})
+	statements := []string{
+		"PRAGMA foreign_keys = ON",
+		"CREATE UNIQUE INDEX idx_widgets_name ON widgets(name)",
+		"CREATE TABLE parts (id INTEGER PRIMARY KEY, widget_id INTEGER REFERENCES widgets(id), label TEXT NOT NULL, weight INTEGER CHECK (weight > 0))",
+	}
+	for _, sql := range statements {
+		if err := DB.Exec(sql).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want ErrorKind
+	}{
+		{"not found", DB.First(&widget{}, 99).Error, KindNotFound},
+		{"unique", DB.Create(&widget{Name: "gear"}).Error, KindConflict},
+		{"foreign key", DB.Exec("INSERT INTO parts (widget_id, label, weight) VALUES (99, 'x', 1)").Error, KindConstraint},
+		{"not null", DB.Exec("INSERT INTO parts (widget_id, weight) VALUES (1, 1)").Error, KindConstraint},
+		{"check", DB.Exec("INSERT INTO parts (widget_id, label, weight) VALUES (1, 'x', 0)").Error, KindConstraint},
+		{"gorm duplicated key", gorm.ErrDuplicatedKey, KindConflict},
+		{"gorm foreign key", gorm.ErrForeignKeyViolated, KindConstraint},
+		{"postgres duplicate", errors.New(`ERROR: duplicate key value violates unique constraint "users_email_key"`), KindConflict},
+		{"mysql duplicate", errors.New("Error 1062: Duplicate entry 'ana' for key 'username'"), KindConflict},
+		{"postgres not null", errors.New(`null value in column "name" violates not-null constraint`), KindConstraint},
+		{"unclassified", DB.Exec("SELECT * FROM missing").Error, KindInternal},
+		{"classified", NewError(KindBadInput, errors.New("bad")), KindBadInput},
+		{"wrapped", fmt.Errorf("saving: %w", ErrConflict), KindConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err == nil {
+				t.Fatal("the statement did not fail")
+			}
+			err := translateError(tt.err)
+			if got := KindOf(err); got != tt.want {
+				t.Fatalf("KindOf(%v) = %s, want %s", tt.err, got, tt.want)
+			}
+			if tt.want != KindInternal && !errors.Is(err, &Error{Kind: tt.want}) {
+				t.Fatalf("errors.Is(%v, kind %s) = false", err, tt.want)
+			}
+			if tt.want == KindInternal && err != tt.err {
+				t.Fatalf("unclassified error changed to %v", err)
+			}
+		})
+	}
+
+	if translateError(nil) != nil {
+		t.Fatal("translateError(nil) != nil")
+	}
+}
+
+func TestRepositoryErrorKinds(t *testing.T) {
+	openTestDB(t, widget{Name: "gear", Rank: 1})
+	if err := DB.Exec("CREATE UNIQUE INDEX idx_widgets_name ON widgets(name)").Error; err != nil {
+		t.Fatal(err)
+	}
+	repo := NewRepository[widget]()
+
+	_, err := repo.GetByID(99)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetByID of a missing item = %v, want ErrNotFound", err)
+	}
+	_, err = repo.Delete(99)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete of a missing item = %v, want ErrNotFound", err)
+	}
+	_, err = repo.Create(&widget{Name: "gear"})
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("Create of a duplicate = %v, want ErrConflict", err)
+	}
+	_, err = repo.Update(&widget{ID: 1, Name: "bolt"})
+	if err != nil {
+		t.Errorf("Update = %v", err)
+	}
+	if errors.Is(ErrNotFound, ErrConflict) {
+		t.Error("errors of different kinds match")
+	}
+}
@@ -0,0 +1,163 @@
+package repositories
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm/schema"
+)
+
+var schemaCache = &sync.Map{}
+
+// Field describes a model field as seen by API clients
+type Field struct {
+	// Name is the Go struct field name
+	Name string
+	// JSONName is the name used in request and response bodies
+	JSONName string
+	// Column is the database column, empty for associations and ignored fields
+	Column string
+	// Type is the Go type of the field
+	Type reflect.Type
+}
+
+// ParseSchema returns the GORM schema of a model
+func ParseSchema(model interface{}) (*schema.Schema, error) {
+	var namer schema.Namer = schema.NamingStrategy{}
+	if DB != nil {
+		namer = DB.NamingStrategy
+	}
+	return schema.Parse(model, schemaCache, namer)
+}
+
+// Fields returns the fields of T keyed by their JSON name
+func Fields[T any]() (map[string]*Field, error) {
+	s, err := ParseSchema(CreateNewElement[T]())
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]*Field{}
+	for _, f := range s.Fields {
+		name := jsonName(f)
+		if name == "-" {
+			continue
+		}
+		field := &Field{Name: f.Name, JSONName: name, Type: f.FieldType}
+		if f.Readable {
+			field.Column = f.DBName
+		}
+		fields[name] = field
+	}
+	return fields, nil
+}
+
+// AssociationPath resolves a dotted path of JSON names, like
+// "checkins_of_agent.apartment", into the association path used by GORM
+// Preload, like "CheckinsOfAgent.Apartment"
+func AssociationPath[T any](jsonPath string) (string, error) {
+	s, err := ParseSchema(CreateNewElement[T]())
+	if err != nil {
+		return "", err
+	}
+
+	names := []string{}
+	for _, segment := range strings.Split(jsonPath, ".") {
+		var found *schema.Relationship
+		for name, rel := range s.Relationships.Relations {
+			if jsonName(s.FieldsByName[name]) == segment {
+				found = rel
+				break
+			}
+		}
+		if found == nil {
+			return "", fmt.Errorf("%s has no association %s", s.Name, segment)
+		}
+		names = append(names, found.Name)
+		s = found.FieldSchema
+	}
+	return strings.Join(names, "."), nil
+}
+
+// ForeignKey returns the JSON name of the field of C referencing P through a
+// has one or has many association of P. When P has several associations to
+// C, the one whose JSON name is association is used.
+func ForeignKey[P, C any](association string) (string, error) {
+	s, err := ParseSchema(CreateNewElement[P]())
+	if err != nil {
+		return "", err
+	}
+	child := reflect.TypeOf(CreateNewElement[C]()).Elem()
+
+	candidates := []*schema.Relationship{}
+	for name, rel := range s.Relationships.Relations {
+		if rel.FieldSchema.ModelType != child || (rel.Type != schema.HasMany && rel.Type != schema.HasOne) {
+			continue
+		}
+		if jsonName(s.FieldsByName[name]) == association {
+			candidates = []*schema.Relationship{rel}
+			break
+		}
+		candidates = append(candidates, rel)
+	}
+	switch {
+	case len(candidates) == 0:
+		return "", fmt.Errorf("%s has no association to %s", s.Name, child.Name())
+	case len(candidates) > 1:
+		return "", fmt.Errorf("%s has several associations to %s", s.Name, child.Name())
+	case len(candidates[0].References) != 1:
+		return "", fmt.Errorf("%s.%s has a composite foreign key", s.Name, candidates[0].Name)
+	}
+	return jsonName(candidates[0].References[0].ForeignKey), nil
+}
+
+// jsonName returns the name of a field in JSON bodies, "-" when it is hidden
+func jsonName(f *schema.Field) string {
+	if f == nil {
+		return "-"
+	}
+	name := f.Name
+	if tag := f.StructField.Tag.Get("json"); tag != "" {
+		name = strings.Split(tag, ",")[0]
+	}
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+// ParseValue converts a string coming from a URL into a value of type t
+func ParseValue(t reflect.Type, raw string) (interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return time.Parse(time.RFC3339Nano, raw)
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.ParseInt(raw, 10, 64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseUint(raw, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(raw, 64)
+	case reflect.Bool:
+		return strconv.ParseBool(raw)
+	case reflect.String:
+		return raw, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// formatValue is the inverse of ParseValue
+func formatValue(value interface{}) string {
+	if t, ok := value.(time.Time); ok {
+		return t.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(value)
+}
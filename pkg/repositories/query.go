@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Operator is a comparison a Filter applies to a column
+type Operator string
+
+const (
+	Eq     Operator = "eq"
+	Ne     Operator = "ne"
+	Lt     Operator = "lt"
+	Lte    Operator = "lte"
+	Gt     Operator = "gt"
+	Gte    Operator = "gte"
+	In     Operator = "in"
+	Like   Operator = "like"
+	IsNull Operator = "is_null"
+)
+
+// Operators lists every supported operator
+var Operators = []Operator{Eq, Ne, Lt, Lte, Gt, Gte, In, Like, IsNull}
+
+// Filter restricts a listing to the rows whose column matches a value.
+// Value must be a []interface{} for In and a bool for IsNull.
+type Filter struct {
+	Column   string
+	Operator Operator
+	Value    interface{}
+}
+
+// Sort orders a listing by a column
+type Sort struct {
+	Column string
+	Desc   bool
+}
+
+// Query describes which items a list operation must return
+type Query struct {
+	// Limit caps the number of items returned, no limit when zero
+	Limit int
+	// Offset skips the first items of the list
+	Offset int
+	// Cursor resumes a keyset listing, see GetAllByCursor
+	Cursor string
+	// CursorColumn is the column keyset listings are ordered by, the primary key when empty
+	CursorColumn string
+	// Filters are combined with AND
+	Filters []Filter
+	// Sorts are applied in order, ties are broken by primary key
+	Sorts []Sort
+	// Columns restricts the selected columns, all of them when empty. The
+	// primary key is always selected.
+	Columns []string
+	// Preloads are association paths loaded along with the items, on top of
+	// the ones set with SetPreloads
+	Preloads []string
+}
+
+// expression builds the WHERE condition of the filter. Columns are quoted by
+// GORM and values are always bound, so no raw SQL reaches the database.
+func (f Filter) expression() (clause.Expression, error) {
+	column := clause.Column{Table: clause.CurrentTable, Name: f.Column}
+	switch f.Operator {
+	case Eq:
+		return clause.Eq{Column: column, Value: f.Value}, nil
+	case Ne:
+		return clause.Neq{Column: column, Value: f.Value}, nil
+	case Lt:
+		return clause.Lt{Column: column, Value: f.Value}, nil
+	case Lte:
+		return clause.Lte{Column: column, Value: f.Value}, nil
+	case Gt:
+		return clause.Gt{Column: column, Value: f.Value}, nil
+	case Gte:
+		return clause.Gte{Column: column, Value: f.Value}, nil
+	case In:
+		values, ok := f.Value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("operator in on %s needs a list of values", f.Column)
+		}
+		return clause.IN{Column: column, Values: values}, nil
+	case Like:
+		return clause.Like{Column: column, Value: f.Value}, nil
+	case IsNull:
+		isNull, ok := f.Value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator is_null on %s needs a boolean", f.Column)
+		}
+		if isNull {
+			return clause.Eq{Column: column, Value: nil}, nil
+		}
+		return clause.Neq{Column: column, Value: nil}, nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", f.Operator)
+}
+
+// applyColumns restricts the columns selected by db to the ones of q plus
+// the primary key, named primaryKey when known, and the given extra columns.
+// Each column is selected once.
+func applyColumns(db *gorm.DB, q *Query, primaryKey string, extra ...string) *gorm.DB {
+	if len(q.Columns) == 0 {
+		return db
+	}
+	columns := []string{}
+	seen := map[string]bool{}
+	for _, column := range append(append([]string{}, extra...), q.Columns...) {
+		if !seen[column] {
+			seen[column] = true
+			columns = append(columns, column)
+		}
+	}
+	if primaryKey == "" || !seen[primaryKey] {
+		columns = append([]string{clause.PrimaryKey}, columns...)
+	}
+	return db.Select(columns)
+}
+
+// applyFilters adds the filters of q to db
+func applyFilters(db *gorm.DB, q *Query) (*gorm.DB, error) {
+	for _, f := range q.Filters {
+		expr, err := f.expression()
+		if err != nil {
+			return db, NewError(KindBadInput, err)
+		}
+		db = db.Where(expr)
+	}
+	return db, nil
+}
@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestGetAllByQueryFilters(t *testing.T) {
+	note := "fragile"
+	openTestDB(t,
+		widget{Name: "gear", Rank: 1, Kind: "a", Note: &note},
+		widget{Name: "gasket", Rank: 2, Kind: "b"},
+		widget{Name: "bolt", Rank: 3, Kind: "a"},
+		widget{Name: "nut", Rank: 4, Kind: "b", Note: &note},
+	)
+	repo := NewRepository[widget]()
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []int64
+	}{
+		{"eq", Filter{Column: "kind", Operator: Eq, Value: "a"}, []int64{1, 3}},
+		{"ne", Filter{Column: "kind", Operator: Ne, Value: "a"}, []int64{2, 4}},
+		{"lt", Filter{Column: "rank", Operator: Lt, Value: int64(2)}, []int64{1}},
+		{"lte", Filter{Column: "rank", Operator: Lte, Value: int64(2)}, []int64{1, 2}},
+		{"gt", Filter{Column: "rank", Operator: Gt, Value: int64(3)}, []int64{4}},
+		{"gte", Filter{Column: "rank", Operator: Gte, Value: int64(3)}, []int64{3, 4}},
+		{"in", Filter{Column: "rank", Operator: In, Value: []interface{}{int64(1), int64(4)}}, []int64{1, 4}},
+		{"like", Filter{Column: "name", Operator: Like, Value: "g%"}, []int64{1, 2}},
+		{"is null", Filter{Column: "note", Operator: IsNull, Value: true}, []int64{2, 3}},
+		{"is not null", Filter{Column: "note", Operator: IsNull, Value: false}, []int64{1, 4}},
+		{"quotes are bound", Filter{Column: "name", Operator: Eq, Value: "' OR 1=1 --"}, []int64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, total, err := repo.GetAllByQuery(&Query{Filters: []Filter{tt.filter}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ids(items); !slices.Equal(got, tt.want) || total != int64(len(tt.want)) {
+				t.Fatalf("got %v (total %d), want %v", got, total, tt.want)
+			}
+		})
+	}
+
+	// Filters are combined with AND
+	items, _, err := repo.GetAllByQuery(&Query{Filters: []Filter{
+		{Column: "kind", Operator: Eq, Value: "b"},
+		{Column: "rank", Operator: Gt, Value: int64(2)},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(items); !slices.Equal(got, []int64{4}) {
+		t.Fatalf("combined filters: got %v, want [4]", got)
+	}
+}
+
+func TestGetAllByQueryInvalidFilters(t *testing.T) {
+	openTestDB(t)
+	repo := NewRepository[widget]()
+
+	tests := []struct {
+		name   string
+		filter Filter
+	}{
+		{"in without list", Filter{Column: "rank", Operator: In, Value: int64(1)}},
+		{"is_null without boolean", Filter{Column: "note", Operator: IsNull, Value: "yes"}},
+		{"unknown operator", Filter{Column: "rank", Operator: "between", Value: int64(1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := repo.GetAllByQuery(&Query{Filters: []Filter{tt.filter}})
+			if KindOf(err) != KindBadInput {
+				t.Fatalf("err = %v, want a bad input error", err)
+			}
+		})
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	var pointer *int64
+	tests := []struct {
+		value interface{}
+		raw   string
+		want  interface{}
+	}{
+		{int(0), "42", int64(42)},
+		{int32(0), "-7", int64(-7)},
+		{pointer, "9", int64(9)},
+		{uint(0), "3", uint64(3)},
+		{float64(0), "1.5", 1.5},
+		{true, "true", true},
+		{"", "go%", "go%"},
+		{time.Time{}, "2024-05-01T10:00:00Z", time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := ParseValue(reflect.TypeOf(tt.value), tt.raw)
+		if err != nil {
+			t.Fatalf("ParseValue(%T, %q): %v", tt.value, tt.raw, err)
+		}
+		if got != tt.want {
+			t.Fatalf("ParseValue(%T, %q) = %#v, want %#v", tt.value, tt.raw, got, tt.want)
+		}
+	}
+
+	invalid := []struct {
+		value interface{}
+		raw   string
+	}{
+		{int(0), "abc"},
+		{int(0), "1.5"},
+		{uint(0), "-1"},
+		{true, "maybe"},
+		{time.Time{}, "2024-05-01"},
+		{[]int{}, "1"},
+	}
+	for _, tt := range invalid {
+		if _, err := ParseValue(reflect.TypeOf(tt.value), tt.raw); err == nil {
+			t.Fatalf("ParseValue(%T, %q) succeeded", tt.value, tt.raw)
+		}
+	}
+}
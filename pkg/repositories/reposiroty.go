@@ -3,8 +3,10 @@ package repositories
 import (
 	"context"
 	"reflect"
+	"strings"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var (
@@ -14,20 +16,53 @@ var (
 
 type IRepository[T any] interface {
 	GetAll() ([]*T, int64, error)
+	GetAllByQuery(q *Query) ([]*T, int64, error)
+	GetAllByCursor(q *Query) ([]*T, *Cursors, error)
 	GetByID(id interface{}) (*T, error)
+	GetByIDWithQuery(id interface{}, q *Query) (*T, error)
 	GetByCriteria(criteria string, args ...interface{}) ([]*T, int64, error)
 	Create(item *T) (*int64, error)
 	Update(item *T) (int64, error)
+	UpdateColumns(item *T, columns []string) (int64, error)
 	Delete(id interface{}) (int64, error)
+	DeleteWithQuery(id interface{}, q *Query) (int64, error)
+	CreateInBatches(items []*T, batchSize int) (int64, error)
+	DeleteByIDs(ids []interface{}) (int64, error)
+	DeleteByIDsWithQuery(ids []interface{}, q *Query) (int64, error)
+	GetByIDForUpdate(id interface{}) (*T, error)
+	Transaction(fn func(repo IRepository[T]) error) error
+
+	// The Context variants run the queries bound to ctx, so cancelling ctx
+	// cancels them
+	GetAllContext(ctx context.Context) ([]*T, int64, error)
+	GetAllByQueryContext(ctx context.Context, q *Query) ([]*T, int64, error)
+	GetAllByCursorContext(ctx context.Context, q *Query) ([]*T, *Cursors, error)
+	GetByIDContext(ctx context.Context, id interface{}) (*T, error)
+	GetByIDWithQueryContext(ctx context.Context, id interface{}, q *Query) (*T, error)
+	GetByCriteriaContext(ctx context.Context, criteria string, args ...interface{}) ([]*T, int64, error)
+	CreateContext(ctx context.Context, item *T) (*int64, error)
+	UpdateContext(ctx context.Context, item *T) (int64, error)
+	UpdateColumnsContext(ctx context.Context, item *T, columns []string) (int64, error)
+	DeleteContext(ctx context.Context, id interface{}) (int64, error)
+	DeleteWithQueryContext(ctx context.Context, id interface{}, q *Query) (int64, error)
+	CreateInBatchesContext(ctx context.Context, items []*T, batchSize int) (int64, error)
+	DeleteByIDsContext(ctx context.Context, ids []interface{}) (int64, error)
+	DeleteByIDsWithQueryContext(ctx context.Context, ids []interface{}, q *Query) (int64, error)
+	GetByIDForUpdateContext(ctx context.Context, id interface{}) (*T, error)
+	TransactionContext(ctx context.Context, fn func(repo IRepository[T]) error) error
+
 	GetTx() *gorm.DB
 	SetTx(tx *gorm.DB)
 	SetPreloads(preloads ...string)
+	SetAssociations(names ...string) error
 }
 
 type Repository[T any] struct {
 	tx       *gorm.DB
 	ctx      context.Context
 	preloads []string
+	// associations are written along with the items, see SetAssociations
+	associations []string
 }
 
 func NewRepository[T any]() *Repository[T] {
@@ -47,70 +82,308 @@ func NewRepositoryWithContext[T any](ctx context.Context) *Repository[T] {
 	return r
 }
 
+// conn returns the connection of the repository bound to ctx, the
+// transaction started by WithTx when ctx holds one
+func (r *Repository[T]) conn(ctx context.Context) *gorm.DB {
+	if tx, ok := TxFrom(ctx); ok {
+		return tx.WithContext(ctx)
+	}
+	return r.tx.WithContext(ctx)
+}
+
 func (r *Repository[T]) GetTx() *gorm.DB {
 	return r.tx
 }
+
+// SetTx replaces the connection of the repository. Repositories are shared
+// between requests, use WithTx for request scoped transactions.
 func (r *Repository[T]) SetTx(tx *gorm.DB) {
 	r.tx = tx
 }
 
 func (r *Repository[T]) GetAll() ([]*T, int64, error) {
+	return r.GetAllContext(r.ctx)
+}
+
+// GetAllContext is GetAll bound to ctx
+func (r *Repository[T]) GetAllContext(ctx context.Context) ([]*T, int64, error) {
 	items := []*T{}
-	db := r.tx
-	if len(r.preloads) > 0 {
-		db = db.Preload(r.preloads[0])
-		for _, preload := range r.preloads[1:] {
-			db = db.Preload(preload)
-		}
+	db := r.withPreloads(r.conn(ctx))
+
+	result := db.Find(&items)
+	return items, result.RowsAffected, translateError(result.Error)
+}
+
+// GetAllByQuery returns the items selected by q along with the total number of
+// items available without limit and offset
+func (r *Repository[T]) GetAllByQuery(q *Query) ([]*T, int64, error) {
+	return r.GetAllByQueryContext(r.ctx, q)
+}
+
+// GetAllByQueryContext is GetAllByQuery bound to ctx
+func (r *Repository[T]) GetAllByQueryContext(ctx context.Context, q *Query) ([]*T, int64, error) {
+	items := []*T{}
+	var total int64
+	db, err := applyFilters(r.conn(ctx), q)
+	if err != nil {
+		return items, 0, err
+	}
+	if err := db.Session(&gorm.Session{}).Model(CreateNewElement[T]()).Count(&total).Error; err != nil {
+		return items, 0, translateError(err)
 	}
 
+	db = r.withQueryPreloads(db, q)
+	if q.Limit > 0 {
+		db = db.Limit(q.Limit)
+	}
+	if q.Offset > 0 {
+		db = db.Offset(q.Offset)
+	}
+	for _, sort := range q.Sorts {
+		db = db.Order(clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: sort.Column}, Desc: sort.Desc})
+	}
+	// Keep pages stable by ordering on the primary key
+	db = db.Order(clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: clause.PrimaryKey}})
+
 	result := db.Find(&items)
-	return items, result.RowsAffected, result.Error
+	return items, total, translateError(result.Error)
 }
 
 func (r *Repository[T]) GetByCriteria(criteria string, args ...interface{}) ([]*T, int64, error) {
+	return r.GetByCriteriaContext(r.ctx, criteria, args...)
+}
+
+// GetByCriteriaContext is GetByCriteria bound to ctx
+func (r *Repository[T]) GetByCriteriaContext(ctx context.Context, criteria string, args ...interface{}) ([]*T, int64, error) {
 	items := []*T{}
-	db := r.tx
-	if len(r.preloads) > 0 {
-		db = db.Preload(r.preloads[0])
-		for _, preload := range r.preloads[1:] {
-			db = db.Preload(preload)
-		}
-	}
+	db := r.withPreloads(r.conn(ctx))
 
 	result := db.Where(criteria, args...).Find(&items)
-	return items, result.RowsAffected, result.Error
+	return items, result.RowsAffected, translateError(result.Error)
 }
 
 func (r *Repository[T]) GetByID(id interface{}) (*T, error) {
+	return r.GetByIDContext(r.ctx, id)
+}
+
+// GetByIDContext is GetByID bound to ctx
+func (r *Repository[T]) GetByIDContext(ctx context.Context, id interface{}) (*T, error) {
 	item := CreateNewElement[T]()
-	db := r.tx
-	if len(r.preloads) > 0 {
-		db = db.Preload(r.preloads[0])
-		for _, preload := range r.preloads[1:] {
-			db = db.Preload(preload)
-		}
+	db := r.withPreloads(r.conn(ctx))
+
+	result := db.First(item, "id = ?", id)
+	return item, translateError(result.Error)
+}
+
+// GetByIDWithQuery returns the item with the given ID, honouring the columns
+// and the filters of q: an item not matching them is not found
+func (r *Repository[T]) GetByIDWithQuery(id interface{}, q *Query) (*T, error) {
+	return r.GetByIDWithQueryContext(r.ctx, id, q)
+}
+
+// GetByIDWithQueryContext is GetByIDWithQuery bound to ctx
+func (r *Repository[T]) GetByIDWithQueryContext(ctx context.Context, id interface{}, q *Query) (*T, error) {
+	item := CreateNewElement[T]()
+	db, err := applyFilters(r.conn(ctx), q)
+	if err != nil {
+		return item, err
 	}
+	db = r.withQueryPreloads(db, q)
 
 	result := db.First(item, "id = ?", id)
-	return item, result.Error
+	return item, translateError(result.Error)
 }
 
+// GetByIDForUpdate returns the item with the given ID, locking its row until
+// the end of the transaction on databases supporting it
+func (r *Repository[T]) GetByIDForUpdate(id interface{}) (*T, error) {
+	return r.GetByIDForUpdateContext(r.ctx, id)
+}
+
+// GetByIDForUpdateContext is GetByIDForUpdate bound to ctx
+func (r *Repository[T]) GetByIDForUpdateContext(ctx context.Context, id interface{}) (*T, error) {
+	item := CreateNewElement[T]()
+	result := r.conn(ctx).Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).First(item, "id = ?", id)
+	return item, translateError(result.Error)
+}
+
+// Transaction runs fn with a repository bound to a new transaction, which is
+// committed when fn returns nil and rolled back otherwise
+func (r *Repository[T]) Transaction(fn func(repo IRepository[T]) error) error {
+	return r.TransactionContext(r.ctx, fn)
+}
+
+// TransactionContext is Transaction bound to ctx. Within a WithTx
+// transaction it runs in a savepoint.
+func (r *Repository[T]) TransactionContext(ctx context.Context, fn func(repo IRepository[T]) error) error {
+	return transaction(ctx, r.conn(ctx), func(ctx context.Context, tx *gorm.DB) error {
+		return fn(&Repository[T]{tx: tx, ctx: ctx, preloads: r.preloads, associations: r.associations})
+	})
+}
+
+// Create inserts item, along with the associations set with SetAssociations
 func (r *Repository[T]) Create(item *T) (*int64, error) {
-	result := r.tx.Create(item)
+	return r.CreateContext(r.ctx, item)
+}
+
+// CreateContext is Create bound to ctx
+func (r *Repository[T]) CreateContext(ctx context.Context, item *T) (*int64, error) {
+	_, err := r.write(ctx, []*T{item}, func(db *gorm.DB) *gorm.DB {
+		return db.Create(item)
+	})
 	id := reflect.ValueOf(item).Elem().FieldByName("ID").Interface().(int64)
-	return &id, result.Error
+	return &id, err
 }
 
+// Update saves every column of item, along with the associations set with
+// SetAssociations
 func (r *Repository[T]) Update(item *T) (int64, error) {
-	result := r.tx.Save(item)
-	return result.RowsAffected, result.Error
+	return r.UpdateContext(r.ctx, item)
+}
+
+// UpdateContext is Update bound to ctx
+func (r *Repository[T]) UpdateContext(ctx context.Context, item *T) (int64, error) {
+	return r.write(ctx, []*T{item}, func(db *gorm.DB) *gorm.DB {
+		return db.Save(item)
+	})
+}
+
+// CreateInBatches inserts items with one statement per batchSize items, all
+// in one transaction. The IDs are set on the items.
+func (r *Repository[T]) CreateInBatches(items []*T, batchSize int) (int64, error) {
+	return r.CreateInBatchesContext(r.ctx, items, batchSize)
+}
+
+// CreateInBatchesContext is CreateInBatches bound to ctx
+func (r *Repository[T]) CreateInBatchesContext(ctx context.Context, items []*T, batchSize int) (int64, error) {
+	if len(items) == 0 {
+		return 0, nil
+	}
+	return r.write(ctx, items, func(db *gorm.DB) *gorm.DB {
+		return db.CreateInBatches(items, batchSize)
+	})
+}
+
+// UpdateColumns writes only the given columns of item, zero values included,
+// leaving the other columns and the associations untouched
+func (r *Repository[T]) UpdateColumns(item *T, columns []string) (int64, error) {
+	return r.UpdateColumnsContext(r.ctx, item, columns)
+}
+
+// UpdateColumnsContext is UpdateColumns bound to ctx
+func (r *Repository[T]) UpdateColumnsContext(ctx context.Context, item *T, columns []string) (int64, error) {
+	result := r.conn(ctx).Model(item).Select(columns).Updates(item)
+	return result.RowsAffected, translateError(result.Error)
 }
 
 func (r *Repository[T]) Delete(id interface{}) (int64, error) {
+	return r.DeleteContext(r.ctx, id)
+}
+
+// DeleteContext is Delete bound to ctx
+func (r *Repository[T]) DeleteContext(ctx context.Context, id interface{}) (int64, error) {
 	item := CreateNewElement[T]()
-	result := r.tx.Delete(item, "id = ?", id)
-	return result.RowsAffected, result.Error
+	result := r.conn(ctx).Delete(item, "id = ?", id)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return 0, ErrNotFound
+	}
+	return result.RowsAffected, translateError(result.Error)
+}
+
+// DeleteWithQuery deletes the item with the given ID when it matches the
+// filters of q, failing with ErrNotFound otherwise. The filters are part of
+// the DELETE statement, so the item cannot change between check and delete.
+func (r *Repository[T]) DeleteWithQuery(id interface{}, q *Query) (int64, error) {
+	return r.DeleteWithQueryContext(r.ctx, id, q)
+}
+
+// DeleteWithQueryContext is DeleteWithQuery bound to ctx
+func (r *Repository[T]) DeleteWithQueryContext(ctx context.Context, id interface{}, q *Query) (int64, error) {
+	db, err := applyFilters(r.conn(ctx), q)
+	if err != nil {
+		return 0, err
+	}
+	item := CreateNewElement[T]()
+	result := db.Delete(item, "id = ?", id)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return 0, ErrNotFound
+	}
+	return result.RowsAffected, translateError(result.Error)
+}
+
+// DeleteByIDs deletes the items with the given IDs with one statement,
+// returning the number of items deleted
+func (r *Repository[T]) DeleteByIDs(ids []interface{}) (int64, error) {
+	return r.DeleteByIDsContext(r.ctx, ids)
+}
+
+// DeleteByIDsContext is DeleteByIDs bound to ctx
+func (r *Repository[T]) DeleteByIDsContext(ctx context.Context, ids []interface{}) (int64, error) {
+	return r.DeleteByIDsWithQueryContext(ctx, ids, &Query{})
+}
+
+// DeleteByIDsWithQuery deletes the items with the given IDs matching the
+// filters of q with one statement, returning the number of items deleted.
+// The filters are part of the DELETE statement, like in DeleteWithQuery.
+func (r *Repository[T]) DeleteByIDsWithQuery(ids []interface{}, q *Query) (int64, error) {
+	return r.DeleteByIDsWithQueryContext(r.ctx, ids, q)
+}
+
+// DeleteByIDsWithQueryContext is DeleteByIDsWithQuery bound to ctx
+func (r *Repository[T]) DeleteByIDsWithQueryContext(ctx context.Context, ids []interface{}, q *Query) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	db, err := applyFilters(r.conn(ctx), q)
+	if err != nil {
+		return 0, err
+	}
+	item := CreateNewElement[T]()
+	result := db.Where(clause.IN{Column: clause.PrimaryColumn, Values: ids}).Delete(item)
+	return result.RowsAffected, translateError(result.Error)
+}
+
+func (r *Repository[T]) withPreloads(db *gorm.DB) *gorm.DB {
+	for _, preload := range r.preloads {
+		db = db.Preload(preload)
+	}
+	return db
+}
+
+// withQueryPreloads adds the preloads and the selected columns of q to db.
+// When columns are restricted, the keys the preloaded associations are
+// joined on are selected too.
+func (r *Repository[T]) withQueryPreloads(db *gorm.DB, q *Query, extra ...string) *gorm.DB {
+	db = r.withPreloads(db)
+	for _, preload := range q.Preloads {
+		db = db.Preload(preload)
+	}
+	if len(q.Columns) == 0 {
+		return db
+	}
+
+	s, err := ParseSchema(CreateNewElement[T]())
+	if err != nil {
+		return applyColumns(db, q, "", extra...)
+	}
+	primaryKey := ""
+	if s.PrioritizedPrimaryField != nil {
+		primaryKey = s.PrioritizedPrimaryField.DBName
+	}
+	for _, preload := range append(append([]string{}, r.preloads...), q.Preloads...) {
+		rel, ok := s.Relationships.Relations[strings.Split(preload, ".")[0]]
+		if !ok {
+			continue
+		}
+		for _, ref := range rel.References {
+			if ref.OwnPrimaryKey && ref.PrimaryKey != nil {
+				extra = append(extra, ref.PrimaryKey.DBName)
+			} else if !ref.OwnPrimaryKey && ref.ForeignKey != nil && ref.ForeignKey.Schema == s {
+				extra = append(extra, ref.ForeignKey.DBName)
+			}
+		}
+	}
+	return applyColumns(db, q, primaryKey, extra...)
 }
 
 func CreateNewElement[T any]() *T {
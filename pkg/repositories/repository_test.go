@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// widget is the model the repository tests run against
+type widget struct {
+	ID   int64   `json:"id" gorm:"primaryKey"`
+	Name string  `json:"name" gorm:"column:name"`
+	Rank int     `json:"rank" gorm:"column:rank"`
+	Kind string  `json:"kind" gorm:"column:kind"`
+	Note *string `json:"note" gorm:"column:note"`
+}
+
+// openTestDB points DB to a new in-memory database holding the widgets
+func openTestDB(t *testing.T, widgets ...widget) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: opens a distinct database
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(&widget{}); err != nil {
+		t.Fatal(err)
+	}
+	for i := range widgets {
+		if err := db.Create(&widgets[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	DB = db
+}
+
+// ids returns the IDs of items, in order
+func ids(items []*widget) []int64 {
+	result := []int64{}
+	for _, item := range items {
+		result = append(result, item.ID)
+	}
+	return result
+}
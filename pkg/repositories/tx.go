@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// txKey is the context key holding the transaction started by WithTx
+type txKey struct{}
+
+// WithTx runs fn in a transaction stored in the context passed to it, so
+// every repository called with that context joins the transaction. It is
+// committed when fn returns nil and rolled back otherwise. Nested calls run
+// in a savepoint of the enclosing transaction.
+func WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return transaction(ctx, Conn(ctx), func(ctx context.Context, _ *gorm.DB) error {
+		return fn(ctx)
+	})
+}
+
+// WithTxOn is WithTx starting the transaction on db when ctx holds none, as
+// for repositories given their own connection with SetTx
+func WithTxOn(ctx context.Context, db *gorm.DB, fn func(ctx context.Context) error) error {
+	if tx, ok := TxFrom(ctx); ok {
+		db = tx
+	}
+	return transaction(ctx, db, func(ctx context.Context, _ *gorm.DB) error {
+		return fn(ctx)
+	})
+}
+
+// TxFrom returns the transaction stored in ctx by WithTx
+func TxFrom(ctx context.Context) (*gorm.DB, bool) {
+	tx, ok := ctx.Value(txKey{}).(*gorm.DB)
+	return tx, ok
+}
+
+// Conn returns the connection queries bound to ctx should use: the
+// transaction of ctx if any, DB otherwise
+func Conn(ctx context.Context) *gorm.DB {
+	if tx, ok := TxFrom(ctx); ok {
+		return tx.WithContext(ctx)
+	}
+	return DB.WithContext(ctx)
+}
+
+// transaction runs fn in a transaction of db, passing it along with a
+// context holding it
+func transaction(ctx context.Context, db *gorm.DB, fn func(ctx context.Context, tx *gorm.DB) error) error {
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx), tx)
+	})
+	return translateError(err)
+}
@@ -0,0 +1,87 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// names returns the names of the stored widgets, by ID
+func names(t *testing.T) []string {
+	t.Helper()
+	result := []string{}
+	if err := DB.Model(&widget{}).Order("id").Pluck("name", &result).Error; err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestWithTx(t *testing.T) {
+	errFailed := errors.New("failed")
+	// create runs on the tables of the database of the current subtest
+	create := func(ctx context.Context, name string) error {
+		_, err := NewRepository[widget]().CreateContext(ctx, &widget{Name: name})
+		return err
+	}
+
+	tests := []struct {
+		name string
+		fn   func(ctx context.Context) error
+		err  error
+		want []string
+	}{
+		{"commit", func(ctx context.Context) error {
+			return create(ctx, "gear")
+		}, nil, []string{"gear"}},
+		{"rollback", func(ctx context.Context) error {
+			if err := create(ctx, "gear"); err != nil {
+				return err
+			}
+			return errFailed
+		}, errFailed, []string{}},
+		// A failed nested call rolls back its savepoint only
+		{"nested rollback", func(ctx context.Context) error {
+			if err := create(ctx, "gear"); err != nil {
+				return err
+			}
+			err := WithTx(ctx, func(ctx context.Context) error {
+				if err := create(ctx, "bolt"); err != nil {
+					return err
+				}
+				return errFailed
+			})
+			if !errors.Is(err, errFailed) {
+				t.Errorf("nested err = %v, want %v", err, errFailed)
+			}
+			return create(ctx, "nut")
+		}, nil, []string{"gear", "nut"}},
+		{"nested commit", func(ctx context.Context) error {
+			if err := WithTx(ctx, func(ctx context.Context) error { return create(ctx, "bolt") }); err != nil {
+				return err
+			}
+			return errFailed
+		}, errFailed, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			openTestDB(t)
+			err := WithTx(context.Background(), func(ctx context.Context) error {
+				if _, ok := TxFrom(ctx); !ok {
+					t.Error("no transaction in the context")
+				}
+				return tt.fn(ctx)
+			})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if got := names(t); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("widgets = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, ok := TxFrom(context.Background()); ok {
+		t.Error("transaction found outside WithTx")
+	}
+}
@@ -1,14 +1,47 @@
 package services
 
-import "github.com/arturoeanton/go-struc2fiber/pkg/repositories"
+import (
+	"context"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/repositories"
+)
 
 type IService[T any] interface {
 	GetAll() ([]*T, int64, error)
+	GetAllByQuery(q *repositories.Query) ([]*T, int64, error)
+	GetAllByCursor(q *repositories.Query) ([]*T, *repositories.Cursors, error)
 	GetByID(id interface{}) (*T, error)
+	GetByIDWithQuery(id interface{}, q *repositories.Query) (*T, error)
 	GetByCriteria(criteria string, args ...interface{}) ([]*T, int64, error)
 	Create(item *T) (int64, error)
 	Update(item *T) (int64, error)
+	UpdateColumns(item *T, columns []string) (int64, error)
 	Delete(id interface{}) (int64, error)
+	DeleteWithQuery(id interface{}, q *repositories.Query) (int64, error)
+	CreateInBatches(items []*T, batchSize int) (int64, error)
+	DeleteByIDs(ids []interface{}) (int64, error)
+	DeleteByIDsWithQuery(ids []interface{}, q *repositories.Query) (int64, error)
+	Modify(id interface{}, fn func(item *T) error) (int64, error)
+
+	// The Context variants run the queries bound to ctx, so cancelling ctx
+	// cancels them
+	GetAllContext(ctx context.Context) ([]*T, int64, error)
+	GetAllByQueryContext(ctx context.Context, q *repositories.Query) ([]*T, int64, error)
+	GetAllByCursorContext(ctx context.Context, q *repositories.Query) ([]*T, *repositories.Cursors, error)
+	GetByIDContext(ctx context.Context, id interface{}) (*T, error)
+	GetByIDWithQueryContext(ctx context.Context, id interface{}, q *repositories.Query) (*T, error)
+	GetByCriteriaContext(ctx context.Context, criteria string, args ...interface{}) ([]*T, int64, error)
+	CreateContext(ctx context.Context, item *T) (int64, error)
+	UpdateContext(ctx context.Context, item *T) (int64, error)
+	UpdateColumnsContext(ctx context.Context, item *T, columns []string) (int64, error)
+	DeleteContext(ctx context.Context, id interface{}) (int64, error)
+	DeleteWithQueryContext(ctx context.Context, id interface{}, q *repositories.Query) (int64, error)
+	CreateInBatchesContext(ctx context.Context, items []*T, batchSize int) (int64, error)
+	DeleteByIDsContext(ctx context.Context, ids []interface{}) (int64, error)
+	DeleteByIDsWithQueryContext(ctx context.Context, ids []interface{}, q *repositories.Query) (int64, error)
+	ModifyContext(ctx context.Context, id interface{}, fn func(ctx context.Context, item *T) error) (int64, error)
+
+	SetAssociations(names ...string) error
 }
 
 type Service[T any] struct {
@@ -29,6 +62,49 @@ func (r *Service[T]) GetAll() ([]*T, int64, error) {
 	return items, c, nil
 }
 
+// GetAllContext is GetAll bound to ctx
+func (r *Service[T]) GetAllContext(ctx context.Context) ([]*T, int64, error) {
+	items, c, err := r.repo.GetAllContext(ctx)
+	if err != nil {
+		return nil, c, err
+	}
+	return items, c, nil
+}
+
+func (r *Service[T]) GetAllByQuery(q *repositories.Query) ([]*T, int64, error) {
+	items, c, err := r.repo.GetAllByQuery(q)
+	if err != nil {
+		return nil, c, err
+	}
+	return items, c, nil
+}
+
+// GetAllByQueryContext is GetAllByQuery bound to ctx
+func (r *Service[T]) GetAllByQueryContext(ctx context.Context, q *repositories.Query) ([]*T, int64, error) {
+	items, c, err := r.repo.GetAllByQueryContext(ctx, q)
+	if err != nil {
+		return nil, c, err
+	}
+	return items, c, nil
+}
+
+func (r *Service[T]) GetAllByCursor(q *repositories.Query) ([]*T, *repositories.Cursors, error) {
+	items, cursors, err := r.repo.GetAllByCursor(q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return items, cursors, nil
+}
+
+// GetAllByCursorContext is GetAllByCursor bound to ctx
+func (r *Service[T]) GetAllByCursorContext(ctx context.Context, q *repositories.Query) ([]*T, *repositories.Cursors, error) {
+	items, cursors, err := r.repo.GetAllByCursorContext(ctx, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return items, cursors, nil
+}
+
 func (r *Service[T]) GetByID(id interface{}) (*T, error) {
 	user, err := r.repo.GetByID(id)
 	if err != nil {
@@ -37,6 +113,32 @@ func (r *Service[T]) GetByID(id interface{}) (*T, error) {
 	return user, nil
 }
 
+// GetByIDContext is GetByID bound to ctx
+func (r *Service[T]) GetByIDContext(ctx context.Context, id interface{}) (*T, error) {
+	user, err := r.repo.GetByIDContext(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *Service[T]) GetByIDWithQuery(id interface{}, q *repositories.Query) (*T, error) {
+	item, err := r.repo.GetByIDWithQuery(id, q)
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// GetByIDWithQueryContext is GetByIDWithQuery bound to ctx
+func (r *Service[T]) GetByIDWithQueryContext(ctx context.Context, id interface{}, q *repositories.Query) (*T, error) {
+	item, err := r.repo.GetByIDWithQueryContext(ctx, id, q)
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
 func (r *Service[T]) GetByCriteria(criteria string, args ...interface{}) ([]*T, int64, error) {
 	items, c, err := r.repo.GetByCriteria(criteria, args...)
 	if err != nil {
@@ -45,6 +147,15 @@ func (r *Service[T]) GetByCriteria(criteria string, args ...interface{}) ([]*T,
 	return items, c, nil
 }
 
+// GetByCriteriaContext is GetByCriteria bound to ctx
+func (r *Service[T]) GetByCriteriaContext(ctx context.Context, criteria string, args ...interface{}) ([]*T, int64, error) {
+	items, c, err := r.repo.GetByCriteriaContext(ctx, criteria, args...)
+	if err != nil {
+		return nil, c, err
+	}
+	return items, c, nil
+}
+
 func (r *Service[T]) Create(item *T) (int64, error) {
 	id, err := r.repo.Create(item)
 	if err != nil {
@@ -54,6 +165,16 @@ func (r *Service[T]) Create(item *T) (int64, error) {
 	return *id, nil
 }
 
+// CreateContext is Create bound to ctx
+func (r *Service[T]) CreateContext(ctx context.Context, item *T) (int64, error) {
+	id, err := r.repo.CreateContext(ctx, item)
+	if err != nil {
+		return 0, err
+	}
+
+	return *id, nil
+}
+
 func (r *Service[T]) Update(item *T) (int64, error) {
 	c, err := r.repo.Update(item)
 	if err != nil {
@@ -62,6 +183,32 @@ func (r *Service[T]) Update(item *T) (int64, error) {
 	return c, nil
 }
 
+// UpdateContext is Update bound to ctx
+func (r *Service[T]) UpdateContext(ctx context.Context, item *T) (int64, error) {
+	c, err := r.repo.UpdateContext(ctx, item)
+	if err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+func (r *Service[T]) UpdateColumns(item *T, columns []string) (int64, error) {
+	c, err := r.repo.UpdateColumns(item, columns)
+	if err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// UpdateColumnsContext is UpdateColumns bound to ctx
+func (r *Service[T]) UpdateColumnsContext(ctx context.Context, item *T, columns []string) (int64, error) {
+	c, err := r.repo.UpdateColumnsContext(ctx, item, columns)
+	if err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
 func (r *Service[T]) Delete(id interface{}) (int64, error) {
 	c, err := r.repo.Delete(id)
 	if err != nil {
@@ -69,3 +216,128 @@ func (r *Service[T]) Delete(id interface{}) (int64, error) {
 	}
 	return c, nil
 }
+
+// DeleteContext is Delete bound to ctx
+func (r *Service[T]) DeleteContext(ctx context.Context, id interface{}) (int64, error) {
+	c, err := r.repo.DeleteContext(ctx, id)
+	if err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// DeleteWithQuery deletes the item with the given ID when it matches the
+// filters of q
+func (r *Service[T]) DeleteWithQuery(id interface{}, q *repositories.Query) (int64, error) {
+	c, err := r.repo.DeleteWithQuery(id, q)
+	if err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// DeleteWithQueryContext is DeleteWithQuery bound to ctx
+func (r *Service[T]) DeleteWithQueryContext(ctx context.Context, id interface{}, q *repositories.Query) (int64, error) {
+	c, err := r.repo.DeleteWithQueryContext(ctx, id, q)
+	if err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+func (r *Service[T]) CreateInBatches(items []*T, batchSize int) (int64, error) {
+	c, err := r.repo.CreateInBatches(items, batchSize)
+	if err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// CreateInBatchesContext is CreateInBatches bound to ctx
+func (r *Service[T]) CreateInBatchesContext(ctx context.Context, items []*T, batchSize int) (int64, error) {
+	c, err := r.repo.CreateInBatchesContext(ctx, items, batchSize)
+	if err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+func (r *Service[T]) DeleteByIDs(ids []interface{}) (int64, error) {
+	c, err := r.repo.DeleteByIDs(ids)
+	if err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// DeleteByIDsContext is DeleteByIDs bound to ctx
+func (r *Service[T]) DeleteByIDsContext(ctx context.Context, ids []interface{}) (int64, error) {
+	c, err := r.repo.DeleteByIDsContext(ctx, ids)
+	if err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// DeleteByIDsWithQuery deletes the items with the given IDs matching the
+// filters of q, returning the number of items deleted
+func (r *Service[T]) DeleteByIDsWithQuery(ids []interface{}, q *repositories.Query) (int64, error) {
+	c, err := r.repo.DeleteByIDsWithQuery(ids, q)
+	if err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// DeleteByIDsWithQueryContext is DeleteByIDsWithQuery bound to ctx
+func (r *Service[T]) DeleteByIDsWithQueryContext(ctx context.Context, ids []interface{}, q *repositories.Query) (int64, error) {
+	c, err := r.repo.DeleteByIDsWithQueryContext(ctx, ids, q)
+	if err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// Modify loads the item with the given ID, changes it with fn and saves it,
+// all in one transaction holding the row. An error from fn aborts the update.
+func (r *Service[T]) Modify(id interface{}, fn func(item *T) error) (int64, error) {
+	var c int64
+	err := r.repo.Transaction(func(repo repositories.IRepository[T]) error {
+		item, err := repo.GetByIDForUpdate(id)
+		if err != nil {
+			return err
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+		c, err = repo.Update(item)
+		return err
+	})
+	return c, err
+}
+
+// ModifyContext is Modify bound to ctx. fn gets the context of the
+// transaction, so the queries it runs, like the database checks of a
+// validation, see the row as it is being changed. The transaction is started
+// on the connection of the repository unless ctx already holds one.
+func (r *Service[T]) ModifyContext(ctx context.Context, id interface{}, fn func(ctx context.Context, item *T) error) (int64, error) {
+	var c int64
+	err := repositories.WithTxOn(ctx, r.repo.GetTx(), func(ctx context.Context) error {
+		item, err := r.repo.GetByIDForUpdateContext(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := fn(ctx, item); err != nil {
+			return err
+		}
+		c, err = r.repo.UpdateContext(ctx, item)
+		return err
+	})
+	return c, err
+}
+
+// SetAssociations sets the associations written along with the items, see
+// repositories.Repository.SetAssociations
+func (r *Service[T]) SetAssociations(names ...string) error {
+	return r.repo.SetAssociations(names...)
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/model"
+	"github.com/arturoeanton/go-struc2fiber/pkg/repositories"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB points repositories.DB to a new in-memory database holding
+// skill 1 named go
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: opens a distinct database
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(&model.Skill{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&model.Skill{UserID: 1, Name: "go", Value: 30}).Error; err != nil {
+		t.Fatal(err)
+	}
+	repositories.DB = db
+	return db
+}
+
+func TestServiceUsesRepositoryContext(t *testing.T) {
+	openTestDB(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s := NewService[model.Skill](repositories.NewRepositoryWithContext[model.Skill](ctx))
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"GetAll", func() error { _, _, err := s.GetAll(); return err }},
+		{"GetByID", func() error { _, err := s.GetByID(1); return err }},
+		{"Create", func() error { _, err := s.Create(&model.Skill{Name: "c"}); return err }},
+		{"Update", func() error { _, err := s.Update(&model.Skill{ID: 1, Name: "c"}); return err }},
+		{"Delete", func() error { _, err := s.Delete(1); return err }},
+		{"Modify", func() error {
+			_, err := s.Modify(1, func(item *model.Skill) error { item.Value = 1; return nil })
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, context.Canceled) {
+				t.Fatalf("err = %v, want the context of the repository canceled", err)
+			}
+		})
+	}
+}
+
+func TestServiceUsesRepositoryConnection(t *testing.T) {
+	db := openTestDB(t)
+	tx := db.Begin()
+	repo := repositories.NewRepository[model.Skill]()
+	repo.SetTx(tx)
+	s := NewService[model.Skill](repo)
+
+	if _, err := s.Create(&model.Skill{UserID: 1, Name: "c", Value: 20}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Modify(1, func(item *model.Skill) error { item.Value = 31; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	_, err := s.ModifyContext(context.Background(), 2, func(ctx context.Context, item *model.Skill) error {
+		item.Value = 21
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, total, err := s.GetAll()
+	if err != nil || total != 2 || items[0].Value != 31 || items[1].Value != 21 {
+		t.Fatalf("in the transaction: %d items, %v", total, err)
+	}
+
+	// Every write ran in the transaction of the repository
+	if err := tx.Rollback().Error; err != nil {
+		t.Fatal(err)
+	}
+	var stored []model.Skill
+	if err := db.Find(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 || stored[0].Value != 30 {
+		t.Fatalf("skills = %+v, want skill go unchanged", stored)
+	}
+}
@@ -0,0 +1,252 @@
+package validator
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Constraint types
+const (
+	ConstraintCompare      = "compare"
+	ConstraintRequiredIf   = "requiredIf"
+	ConstraintRequiredWith = "requiredWith"
+	ConstraintExclusive    = "exclusive"
+)
+
+// Constraint is a schema level rule relating several fields, e.g.
+//
+//	constraints:
+//	  - type: compare
+//	    field: end
+//	    operator: gte
+//	    other: start
+type Constraint struct {
+	// Type is compare, requiredIf, requiredWith or exclusive
+	Type string `yaml:"type"`
+	// Field is the field the constraint is reported on
+	Field string `yaml:"field,omitempty"`
+	// Operator compares Field with Other: eq, ne, gt, gte, lt or lte
+	Operator string `yaml:"operator,omitempty"`
+	// Other is the field Field is compared with, or the one making it required
+	Other string `yaml:"other,omitempty"`
+	// Value is the value of Other making Field required, any value when omitted
+	Value interface{} `yaml:"value,omitempty"`
+	// Fields are the fields of requiredWith and exclusive constraints
+	Fields      []string `yaml:"fields,omitempty"`
+	Description string   `yaml:"description,omitempty"`
+	Message     Messages `yaml:"message,omitempty"`
+}
+
+var operators = map[string]func(cmp int) bool{
+	"eq":  func(cmp int) bool { return cmp == 0 },
+	"ne":  func(cmp int) bool { return cmp != 0 },
+	"gt":  func(cmp int) bool { return cmp > 0 },
+	"gte": func(cmp int) bool { return cmp >= 0 },
+	"lt":  func(cmp int) bool { return cmp < 0 },
+	"lte": func(cmp int) bool { return cmp <= 0 },
+}
+
+// lookupFunc returns the value of a field and whether it is set
+type lookupFunc func(field string) (interface{}, bool)
+
+// checkConstraints reports the first malformed constraint of schema
+func checkConstraints(schema *ValidationSchema) error {
+	for i, c := range schema.Constraints {
+		switch c.Type {
+		case ConstraintCompare:
+			if _, ok := operators[c.Operator]; !ok {
+				return fmt.Errorf("constraint %d: unknown operator %q", i, c.Operator)
+			}
+			if c.Field == "" || c.Other == "" {
+				return fmt.Errorf("constraint %d: compare needs field and other", i)
+			}
+		case ConstraintRequiredIf:
+			if c.Field == "" || c.Other == "" {
+				return fmt.Errorf("constraint %d: requiredIf needs field and other", i)
+			}
+		case ConstraintRequiredWith:
+			if c.Field == "" || len(c.Fields) == 0 {
+				return fmt.Errorf("constraint %d: requiredWith needs field and fields", i)
+			}
+		case ConstraintExclusive:
+			if len(c.Fields) < 2 {
+				return fmt.Errorf("constraint %d: exclusive needs at least two fields", i)
+			}
+		default:
+			return fmt.Errorf("constraint %d: unknown type %q", i, c.Type)
+		}
+	}
+	for _, rule := range schema.Rules {
+		if rule.Nested != nil {
+			if err := checkConstraints(rule.Nested); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateConstraints evaluates the constraints of the schema, only those
+// involving the given fields when only is not nil
+func (v *StructValidator) validateConstraints(lookup lookupFunc, only map[string]bool) ValidationErrors {
+	errors := ValidationErrors{}
+	if v.schema == nil {
+		return errors
+	}
+
+	for _, c := range v.schema.Constraints {
+		if only != nil && !c.involves(only) {
+			continue
+		}
+
+		var err ValidationError
+		failed := false
+
+		switch c.Type {
+		case ConstraintCompare:
+			value, ok := lookup(c.Field)
+			other, otherOk := lookup(c.Other)
+			if !ok || !otherOk {
+				continue
+			}
+			cmp, comparable := compareValues(value, other)
+			if !comparable || !operators[c.Operator](cmp) {
+				err = newError(c.Field, c.Type, CodeCompare, map[string]interface{}{"operator": c.Operator, "other": c.Other})
+				failed = true
+			}
+
+		case ConstraintRequiredIf:
+			other, ok := lookup(c.Other)
+			if !ok || (c.Value != nil && fmt.Sprintf("%v", other) != fmt.Sprintf("%v", c.Value)) {
+				continue
+			}
+			if _, set := lookup(c.Field); !set {
+				params := map[string]interface{}{"other": c.Other}
+				if c.Value != nil {
+					params["value"] = c.Value
+				}
+				err = newError(c.Field, c.Type, CodeRequiredIf, params)
+				failed = true
+			}
+
+		case ConstraintRequiredWith:
+			if _, set := lookup(c.Field); set {
+				continue
+			}
+			for _, field := range c.Fields {
+				if _, ok := lookup(field); ok {
+					err = newError(c.Field, c.Type, CodeRequiredWith, map[string]interface{}{"fields": c.Fields})
+					failed = true
+					break
+				}
+			}
+
+		case ConstraintExclusive:
+			set := []string{}
+			for _, field := range c.Fields {
+				if _, ok := lookup(field); ok {
+					set = append(set, field)
+				}
+			}
+			if len(set) > 1 {
+				field := c.Field
+				if field == "" {
+					field = set[1]
+				}
+				err = newError(field, c.Type, CodeExclusive, map[string]interface{}{"fields": c.Fields})
+				failed = true
+			}
+		}
+
+		if failed {
+			if template, ok := c.Message.lookup(err); ok {
+				err.template = template
+				err.Message = err.render(DefaultLocale)
+			}
+			errors = append(errors, err)
+		}
+	}
+	return errors
+}
+
+// involves reports whether any field of the constraint is in fields
+func (c *Constraint) involves(fields map[string]bool) bool {
+	if fields[c.Field] || fields[c.Other] {
+		return true
+	}
+	for _, field := range c.Fields {
+		if fields[field] {
+			return true
+		}
+	}
+	return false
+}
+
+// structLookup finds fields of a struct, zero values count as not set
+func (v *StructValidator) structLookup(val reflect.Value, typ reflect.Type) lookupFunc {
+	return func(field string) (interface{}, bool) {
+		fieldVal, _, found := v.findField(val, typ, field)
+		for found && fieldVal.Kind() == reflect.Ptr {
+			if fieldVal.IsNil() {
+				return nil, false
+			}
+			fieldVal = fieldVal.Elem()
+		}
+		if !found || fieldVal.IsZero() {
+			return nil, false
+		}
+		return fieldVal.Interface(), true
+	}
+}
+
+// mapLookup finds fields of a map, nil and empty strings count as not set
+func mapLookup(data map[string]interface{}) lookupFunc {
+	return func(field string) (interface{}, bool) {
+		value, ok := data[field]
+		if !ok || value == nil || value == "" {
+			return nil, false
+		}
+		return value, true
+	}
+}
+
+// compareValues compares numbers, dates and strings, returning -1, 0 or 1.
+// Other values are compared by their textual form.
+func compareValues(a, b interface{}) (int, bool) {
+	if x, ok := toNumber(a); ok {
+		y, ok := toNumber(b)
+		if !ok {
+			return 0, false
+		}
+		return compareOrdered(x, y), true
+	}
+
+	x, y := fmt.Sprintf("%v", a), fmt.Sprintf("%v", b)
+	if tx, ok := parseTime(x); ok {
+		if ty, ok := parseTime(y); ok {
+			return tx.Compare(ty), true
+		}
+	}
+	return compareOrdered(x, y), true
+}
+
+func compareOrdered[V float64 | string](a, b V) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// parseTime parses RFC 3339 dates and date-times
+func parseTime(value string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
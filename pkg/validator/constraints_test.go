@@ -0,0 +1,177 @@
+package validator
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// booking is the struct the constraint tests run against
+type booking struct {
+	Start  string `json:"start"`
+	End    string `json:"end"`
+	Min    int    `json:"min"`
+	Max    int    `json:"max"`
+	Kind   string `json:"kind"`
+	Reason string `json:"reason"`
+	Guest  string `json:"guest"`
+	Note   string `json:"note"`
+	Street string `json:"street"`
+	City   string `json:"city"`
+	Zip    string `json:"zip"`
+	Card   string `json:"card"`
+	Cash   string `json:"cash"`
+}
+
+const bookingSchema = `
+name: Booking
+rules:
+  - field: start
+    type: string
+    format: date
+  - field: end
+    type: string
+    format: date
+constraints:
+  - type: compare
+    field: end
+    operator: gte
+    other: start
+  - type: compare
+    field: max
+    operator: gt
+    other: min
+  - type: requiredIf
+    field: reason
+    other: kind
+    value: sick
+  - type: requiredIf
+    field: note
+    other: guest
+  - type: requiredWith
+    field: zip
+    fields: [street, city]
+  - type: exclusive
+    fields: [card, cash]
+`
+
+func TestConstraints(t *testing.T) {
+	v := mustValidator(t, bookingSchema)
+	valid := booking{Start: "2024-01-10", End: "2024-01-12", Min: 1, Max: 2, Kind: "holiday", Card: "visa"}
+
+	tests := []struct {
+		name string
+		edit func(b *booking)
+		want ValidationErrors
+	}{
+		{"valid", func(b *booking) {}, ValidationErrors{}},
+		{"compare dates equal", func(b *booking) { b.End = b.Start }, ValidationErrors{}},
+		{"compare dates", func(b *booking) { b.End = "2024-01-09" }, ValidationErrors{
+			{Field: "end", Rule: "compare", Code: CodeCompare, Params: map[string]interface{}{"operator": "gte", "other": "start"}},
+		}},
+		{"compare numbers", func(b *booking) { b.Max = 1 }, ValidationErrors{
+			{Field: "max", Rule: "compare", Code: CodeCompare, Params: map[string]interface{}{"operator": "gt", "other": "min"}},
+		}},
+		// Comparisons with an unset field are left to required
+		{"compare unset", func(b *booking) { b.Min, b.Max = 0, 0 }, ValidationErrors{}},
+		{"required if value", func(b *booking) { b.Kind = "sick" }, ValidationErrors{
+			{Field: "reason", Rule: "requiredIf", Code: CodeRequiredIf, Params: map[string]interface{}{"other": "kind", "value": "sick"}},
+		}},
+		{"required if value set", func(b *booking) { b.Kind, b.Reason = "sick", "flu" }, ValidationErrors{}},
+		{"required if present", func(b *booking) { b.Guest = "ana" }, ValidationErrors{
+			{Field: "note", Rule: "requiredIf", Code: CodeRequiredIf, Params: map[string]interface{}{"other": "guest"}},
+		}},
+		{"required if present set", func(b *booking) { b.Guest, b.Note = "ana", "vegan" }, ValidationErrors{}},
+		{"required with", func(b *booking) { b.City = "Rosario" }, ValidationErrors{
+			{Field: "zip", Rule: "requiredWith", Code: CodeRequiredWith, Params: map[string]interface{}{"fields": []string{"street", "city"}}},
+		}},
+		{"required with set", func(b *booking) { b.City, b.Zip = "Rosario", "2000" }, ValidationErrors{}},
+		{"exclusive", func(b *booking) { b.Cash = "100" }, ValidationErrors{
+			{Field: "cash", Rule: "exclusive", Code: CodeExclusive, Params: map[string]interface{}{"fields": []string{"card", "cash"}}},
+		}},
+		{"exclusive none", func(b *booking) { b.Card = "" }, ValidationErrors{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := valid
+			tt.edit(&b)
+			ok, errs := v.ValidateStruct(b)
+			if ok != (len(tt.want) == 0) {
+				t.Fatalf("valid = %v, errors %v", ok, errs)
+			}
+			if got := withoutMessages(errs); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConstraintsOfPartialValidations(t *testing.T) {
+	v := mustValidator(t, bookingSchema)
+	b := booking{Start: "2024-01-10", End: "2024-01-09", Min: 2, Max: 1, Kind: "sick", City: "Rosario", Card: "visa", Cash: "100"}
+
+	tests := []struct {
+		fields []string
+		codes  []string
+	}{
+		{nil, []string{}},
+		{[]string{"start"}, []string{CodeCompare}},
+		{[]string{"end", "min"}, []string{CodeCompare, CodeCompare}},
+		{[]string{"kind"}, []string{CodeRequiredIf}},
+		{[]string{"reason"}, []string{CodeRequiredIf}},
+		{[]string{"city"}, []string{CodeRequiredWith}},
+		{[]string{"cash"}, []string{CodeExclusive}},
+		// Constraints of other fields are not checked
+		{[]string{"note"}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.fields), func(t *testing.T) {
+			_, errs := v.ValidateStructPartial(b, tt.fields)
+			codes := []string{}
+			for _, err := range errs {
+				codes = append(codes, err.Code)
+			}
+			if !reflect.DeepEqual(codes, tt.codes) {
+				t.Fatalf("codes = %v, want %v: %v", codes, tt.codes, errs)
+			}
+		})
+	}
+}
+
+func TestConstraintsOfMaps(t *testing.T) {
+	v := mustValidator(t, bookingSchema)
+
+	_, errs := v.ValidateMap(map[string]interface{}{"min": 5.0, "max": 3.0, "kind": "sick", "reason": "", "card": "visa", "cash": nil})
+	want := ValidationErrors{
+		{Field: "max", Rule: "compare", Code: CodeCompare, Params: map[string]interface{}{"operator": "gt", "other": "min"}},
+		{Field: "reason", Rule: "requiredIf", Code: CodeRequiredIf, Params: map[string]interface{}{"other": "kind", "value": "sick"}},
+	}
+	if got := withoutMessages(errs); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %#v, want %#v", got, want)
+	}
+
+	// Partial map validations check the constraints of the keys sent only
+	if ok, errs := v.ValidateMapPartial(map[string]interface{}{"card": "visa"}); !ok {
+		t.Fatalf("partial map: %v", errs)
+	}
+	if ok, _ := v.ValidateMapPartial(map[string]interface{}{"card": "visa", "cash": "100"}); ok {
+		t.Fatal("partial map: exclusive fields accepted")
+	}
+}
+
+func TestMalformedConstraints(t *testing.T) {
+	tests := []string{
+		"- {type: compare, field: end, operator: after, other: start}",
+		"- {type: compare, field: end, operator: gte}",
+		"- {type: requiredIf, field: reason}",
+		"- {type: requiredWith, field: zip}",
+		"- {type: exclusive, fields: [card]}",
+		"- {type: unknown}",
+	}
+	for _, constraints := range tests {
+		err := NewStructValidator().LoadSchemaFromYAML("name: Booking\nconstraints:\n" + constraints)
+		if err == nil {
+			t.Errorf("%s: malformed constraint accepted", constraints)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package validator
+
+import (
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RuleFunc checks a value against a custom rule, the returned error is used
+// as message unless a catalog has a "custom.<name>" entry
+type RuleFunc func(value interface{}, params map[string]interface{}) error
+
+// customRules are the rules usable with the custom key of schema rules
+var customRules = map[string]RuleFunc{}
+
+// customRulesMu guards customRules, RegisterRule may run while requests are
+// validated
+var customRulesMu sync.RWMutex
+
+// RegisterRule makes fn usable from schemas under the given name, e.g.
+//
+//	custom: [slug, {name: maxWords, params: {max: 3}}]
+//
+// Rules must be registered before loading the schemas that use them.
+func RegisterRule(name string, fn RuleFunc) {
+	customRulesMu.Lock()
+	defer customRulesMu.Unlock()
+	customRules[name] = fn
+}
+
+// lookupRule returns the function of a registered rule
+func lookupRule(name string) (RuleFunc, bool) {
+	customRulesMu.RLock()
+	defer customRulesMu.RUnlock()
+	fn, ok := customRules[name]
+	return fn, ok
+}
+
+// CustomRule references a registered rule from a schema
+type CustomRule struct {
+	Name   string                 `yaml:"name" json:"name"`
+	Params map[string]interface{} `yaml:"params,omitempty" json:"params,omitempty"`
+}
+
+// UnmarshalYAML accepts the rule name alone as well as a name with params
+func (c *CustomRule) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		c.Name = node.Value
+		return nil
+	}
+	type plain CustomRule
+	return node.Decode((*plain)(c))
+}
+
+// validateCustom runs the custom rules of rule on value
+func validateCustom(fieldName string, value interface{}, rule *ValidationRule) ValidationErrors {
+	errors := ValidationErrors{}
+	for _, custom := range rule.Custom {
+		fn, ok := lookupRule(custom.Name)
+		if !ok {
+			continue
+		}
+		if err := fn(value, custom.Params); err != nil {
+			e := ValidationError{
+				Field:    fieldName,
+				Rule:     "custom",
+				Code:     custom.Name,
+				Params:   custom.Params,
+				fallback: err.Error(),
+			}
+			e.Message = e.render(DefaultLocale)
+			errors = append(errors, e)
+		}
+	}
+	return errors
+}
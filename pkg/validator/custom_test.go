@@ -0,0 +1,97 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// maxWords is the custom rule the tests register
+func maxWords(value interface{}, params map[string]interface{}) error {
+	str, _ := value.(string)
+	if max, _ := params["max"].(int); len(strings.Fields(str)) > max {
+		return fmt.Errorf("must have at most %d words", max)
+	}
+	return nil
+}
+
+func TestRegisterRule(t *testing.T) {
+	t.Cleanup(func() {
+		customRulesMu.Lock()
+		delete(customRules, "maxWords")
+		customRulesMu.Unlock()
+		catalogsMu.Lock()
+		delete(catalogs["es"], "custom.maxWords")
+		catalogsMu.Unlock()
+	})
+
+	schema := `
+name: Post
+rules:
+  - field: title
+    type: string
+    custom: [{name: maxWords, params: {max: 3}}]
+`
+	// Unknown rules are rejected when the schema is loaded
+	if err := NewStructValidator().LoadSchemaFromYAML(schema); err == nil {
+		t.Fatal("unknown custom rule accepted")
+	}
+
+	RegisterRule("maxWords", maxWords)
+	v := mustValidator(t, schema)
+
+	tests := []struct {
+		title string
+		valid bool
+	}{
+		{"", true},
+		{"hello world", true},
+		{"one two three", true},
+		{"one two three four", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			valid, errs := v.ValidateMap(map[string]interface{}{"title": tt.title})
+			if valid != tt.valid {
+				t.Fatalf("valid = %v, want %v: %v", valid, tt.valid, errs)
+			}
+			if !valid && (errs[0].Rule != "custom" || errs[0].Code != "maxWords" || errs[0].Message != "must have at most 3 words") {
+				t.Fatalf("error = %+v", errs[0])
+			}
+		})
+	}
+
+	// Catalogs may translate the message of the rule
+	RegisterMessages("es", map[string]string{"custom.maxWords": "El campo '{field}' admite hasta {max} palabras"})
+	_, errs := v.ValidateMap(map[string]interface{}{"title": "uno dos tres cuatro"})
+	if got, want := errs.Localize("es")[0].Message, "El campo 'title' admite hasta 3 palabras"; got != want {
+		t.Errorf("localized message = %q, want %q", got, want)
+	}
+	if got, want := errs.Localize("en")[0].Message, "must have at most 3 words"; got != want {
+		t.Errorf("message = %q, want %q", got, want)
+	}
+}
+
+func TestRegisterRuleConcurrently(t *testing.T) {
+	t.Cleanup(func() {
+		customRulesMu.Lock()
+		delete(customRules, "maxWords")
+		customRulesMu.Unlock()
+	})
+	RegisterRule("maxWords", maxWords)
+	rule := &ValidationRule{FieldName: "title", Custom: []CustomRule{{Name: "maxWords", Params: map[string]interface{}{"max": 1}}}}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			RegisterRule("maxWords", maxWords)
+		}()
+		go func() {
+			defer wg.Done()
+			validateCustom("title", "two words", rule)
+		}()
+	}
+	wg.Wait()
+}
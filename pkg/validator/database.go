@@ -0,0 +1,141 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/repositories"
+	"gorm.io/gorm/clause"
+)
+
+// ExistsRule requires the value of a field to reference an existing row,
+// the column defaults to id
+type ExistsRule struct {
+	Table  string `yaml:"table" json:"table"`
+	Column string `yaml:"column,omitempty" json:"column,omitempty"`
+}
+
+// record locates the row being validated for the unique rules
+type record struct {
+	// table is the table of the row, empty when unknown
+	table string
+	// columns maps the field names of the rules to their columns
+	columns map[string]string
+	// pk is the primary key column and id its value, nil for new rows
+	pk string
+	id interface{}
+}
+
+// column returns the column of a field, the field name itself by default
+func (r *record) column(fieldName string) string {
+	if column, ok := r.columns[fieldName]; ok {
+		return column
+	}
+	return fieldName
+}
+
+// structRecord describes the row of a model struct using its GORM schema
+func structRecord(val reflect.Value, typ reflect.Type) *record {
+	r := &record{columns: map[string]string{}, pk: "id"}
+	s, err := repositories.ParseSchema(reflect.New(typ).Interface())
+	if err != nil {
+		return r
+	}
+	r.table = s.Table
+	for _, f := range s.Fields {
+		if f.DBName == "" {
+			continue
+		}
+		r.columns[f.Name] = f.DBName
+		if tag := strings.Split(f.Tag.Get("json"), ",")[0]; tag != "" && tag != "-" {
+			r.columns[tag] = f.DBName
+		}
+	}
+	if pk := s.PrioritizedPrimaryField; pk != nil {
+		r.pk = pk.DBName
+		if id := val.FieldByIndex(pk.StructField.Index); !id.IsZero() {
+			r.id = reflect.Indirect(id).Interface()
+		}
+	}
+	return r
+}
+
+// mapRecord describes the row of a map using the table of the schema and
+// the id key of the data
+func (v *StructValidator) mapRecord(data map[string]interface{}) *record {
+	r := &record{pk: "id"}
+	if v.schema != nil {
+		r.table = v.schema.Table
+	}
+	if id, ok := data["id"]; ok && id != nil && !reflect.ValueOf(id).IsZero() {
+		r.id = id
+	}
+	return r
+}
+
+// hasDatabaseRules reports whether any rule needs to query the database
+func (v *StructValidator) hasDatabaseRules() bool {
+	for _, rule := range v.rules {
+		if rule.Unique || rule.Exists != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// validateDatabase checks the unique and exists rules of a field against
+// repositories.DB, within the transaction of ctx if any. Nil values are
+// skipped, like the fields not sent, and so are zero values unless the field
+// is required, as optional fields like a foreign key are left zero when
+// omitted. Failed queries are reported as unavailable errors, see
+// ValidationErrors.Err.
+func validateDatabase(ctx context.Context, fieldName string, value interface{}, rule *ValidationRule, r *record) ValidationErrors {
+	errors := ValidationErrors{}
+	if repositories.DB == nil || value == nil {
+		return errors
+	}
+	if !rule.Required && reflect.ValueOf(value).IsZero() {
+		return errors
+	}
+
+	if rule.Unique && r.table != "" {
+		db := repositories.Conn(ctx).Table(r.table).Where(clause.Eq{Column: clause.Column{Name: r.column(fieldName)}, Value: value})
+		if r.id != nil {
+			db = db.Where(clause.Neq{Column: clause.Column{Name: r.pk}, Value: r.id})
+		}
+		var count int64
+		if err := db.Count(&count).Error; err != nil {
+			errors = append(errors, unavailableError(fieldName, "unique", err))
+		} else if count > 0 {
+			errors = append(errors, newError(fieldName, "unique", CodeUnique, nil))
+		}
+	}
+
+	if rule.Exists != nil {
+		column := rule.Exists.Column
+		if column == "" {
+			column = "id"
+		}
+		var count int64
+		err := repositories.Conn(ctx).Table(rule.Exists.Table).
+			Where(clause.Eq{Column: clause.Column{Name: column}, Value: value}).
+			Limit(1).Count(&count).Error
+		if err != nil {
+			errors = append(errors, unavailableError(fieldName, "exists", err))
+		} else if count == 0 {
+			errors = append(errors, newError(fieldName, "exists", CodeExists, map[string]interface{}{"table": rule.Exists.Table, "column": column}))
+		}
+	}
+
+	return errors
+}
+
+// unavailableError reports a rule that could not be checked because its
+// query failed
+func unavailableError(fieldName, rule string, err error) ValidationError {
+	e := newError(fieldName, rule, CodeUnavailable, nil)
+	e.cause = fmt.Errorf("validator: %s check of %s failed: %w", rule, fieldName, err)
+	return e
+}
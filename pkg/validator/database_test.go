@@ -0,0 +1,290 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/repositories"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// account is the model the database rule tests run against
+type account struct {
+	ID       int64  `json:"id" gorm:"primaryKey"`
+	Username string `json:"username" gorm:"column:username"`
+	OwnerID  int64  `json:"owner_id" gorm:"column:owner_id"`
+}
+
+// owner is the row accounts reference
+type owner struct {
+	ID int64 `gorm:"primaryKey"`
+}
+
+const accountSchema = `
+name: Account
+table: accounts
+rules:
+  - field: username
+    type: string
+    unique: true
+  - field: owner_id
+    type: integer
+    exists: {table: owners}
+`
+
+// openTestDB points repositories.DB to a new in-memory database holding
+// owner 1 and the account "ana" of it, until the test ends
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: opens a distinct database
+	sqlDB.SetMaxOpenConns(1)
+	previous := repositories.DB
+	t.Cleanup(func() {
+		repositories.DB = previous
+		sqlDB.Close()
+	})
+
+	if err := db.AutoMigrate(&account{}, &owner{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&owner{ID: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&account{Username: "ana", OwnerID: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+	repositories.DB = db
+	return db
+}
+
+func TestDatabaseRules(t *testing.T) {
+	openTestDB(t)
+	v := mustValidator(t, accountSchema)
+
+	tests := []struct {
+		name string
+		data account
+		want ValidationErrors
+	}{
+		{"valid", account{Username: "bob", OwnerID: 1}, ValidationErrors{}},
+		{"taken", account{Username: "ana", OwnerID: 1}, ValidationErrors{
+			{Field: "username", Rule: "unique", Code: CodeUnique},
+		}},
+		// The row being updated does not conflict with itself
+		{"same row", account{ID: 1, Username: "ana", OwnerID: 1}, ValidationErrors{}},
+		{"other row", account{ID: 2, Username: "ana", OwnerID: 1}, ValidationErrors{
+			{Field: "username", Rule: "unique", Code: CodeUnique},
+		}},
+		{"missing owner", account{Username: "bob", OwnerID: 9}, ValidationErrors{
+			{Field: "owner_id", Rule: "exists", Code: CodeExists, Params: map[string]interface{}{"table": "owners", "column": "id"}},
+		}},
+		// Zero values of optional fields are not checked
+		{"zero owner", account{Username: "bob"}, ValidationErrors{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid, errs := v.ValidateStruct(tt.data)
+			if valid != (len(tt.want) == 0) {
+				t.Fatalf("valid = %v, errors %v", valid, errs)
+			}
+			if got := withoutMessages(errs); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDatabaseRulesOfRequiredFields(t *testing.T) {
+	openTestDB(t)
+	v := mustValidator(t, `
+name: Account
+table: accounts
+rules:
+  - field: owner_id
+    type: integer
+    required: true
+    exists: {table: owners}
+`)
+
+	// Zero values of required fields are checked like any other
+	want := ValidationErrors{
+		{Field: "owner_id", Rule: "exists", Code: CodeExists, Params: map[string]interface{}{"table": "owners", "column": "id"}},
+	}
+	_, errs := v.ValidateStruct(account{Username: "bob"})
+	if got := withoutMessages(errs); !reflect.DeepEqual(got, want) {
+		t.Fatalf("struct: got %#v, want %#v", got, want)
+	}
+	_, errs = v.ValidateMap(map[string]interface{}{"owner_id": 0})
+	if got := withoutMessages(errs); !reflect.DeepEqual(got, want) {
+		t.Fatalf("map: got %#v, want %#v", got, want)
+	}
+}
+
+func TestDatabaseRulesOfMaps(t *testing.T) {
+	openTestDB(t)
+	v := mustValidator(t, accountSchema)
+
+	tests := []struct {
+		name  string
+		data  map[string]interface{}
+		codes []string
+	}{
+		{"absent", map[string]interface{}{}, []string{}},
+		{"zero", map[string]interface{}{"owner_id": 0}, []string{}},
+		{"existing", map[string]interface{}{"owner_id": 1.0}, []string{}},
+		{"taken", map[string]interface{}{"username": "ana"}, []string{CodeUnique}},
+		{"same row", map[string]interface{}{"id": 1, "username": "ana"}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errs := v.ValidateMapPartial(tt.data)
+			codes := []string{}
+			for _, err := range errs {
+				codes = append(codes, err.Code)
+			}
+			if !reflect.DeepEqual(codes, tt.codes) {
+				t.Fatalf("codes = %v, want %v: %v", codes, tt.codes, errs)
+			}
+		})
+	}
+}
+
+func TestDatabaseRulesWithinTransactions(t *testing.T) {
+	openTestDB(t)
+	v := mustValidator(t, accountSchema)
+
+	err := repositories.WithTx(context.Background(), func(ctx context.Context) error {
+		if err := repositories.Conn(ctx).Create(&account{Username: "bob", OwnerID: 1}).Error; err != nil {
+			return err
+		}
+		if valid, _ := v.ValidateStructContext(ctx, account{Username: "bob", OwnerID: 1}); valid {
+			t.Error("row written by the transaction not seen")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDatabaseRuleFailures(t *testing.T) {
+	db := openTestDB(t)
+	v := mustValidator(t, accountSchema)
+	if valid, errs := v.ValidateStruct(account{Username: "bob", OwnerID: 1}); !valid || errs.Err() != nil {
+		t.Fatalf("valid = %v, errors %v", valid, errs)
+	}
+
+	if err := db.Exec("DROP TABLE owners").Error; err != nil {
+		t.Fatal(err)
+	}
+	// Failed queries reject the data rather than letting it through
+	valid, errs := v.ValidateStruct(account{Username: "bob", OwnerID: 1})
+	if valid {
+		t.Fatal("data accepted when the exists check failed")
+	}
+	want := ValidationErrors{{Field: "owner_id", Rule: "exists", Code: CodeUnavailable}}
+	if got := withoutMessages(errs); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %#v, want %#v", got, want)
+	}
+	err := errs.Localize("es").Err()
+	if err == nil || errors.Unwrap(err) == nil {
+		t.Fatalf("Err() = %v, want the failure of the query", err)
+	}
+}
+
+func TestDatabaseRulesWithCanceledContext(t *testing.T) {
+	openTestDB(t)
+	v := mustValidator(t, accountSchema)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	valid, errs := v.ValidateStructContext(ctx, account{Username: "bob", OwnerID: 1})
+	if valid {
+		t.Fatal("data accepted when the context was canceled")
+	}
+	if err := errs.Err(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Err() = %v, want context.Canceled", err)
+	}
+}
+
+func TestDatabaseRulesOfNestedObjects(t *testing.T) {
+	openTestDB(t)
+	v := mustValidator(t, `
+name: Transfer
+rules:
+  - field: from
+    type: object
+    nested:
+      name: Account
+      rules:
+        - field: owner_id
+          type: integer
+          exists: {table: owners}
+  - field: to
+    type: array
+    items:
+      type: object
+      nested:
+        name: Account
+        rules:
+          - field: owner_id
+            type: integer
+            exists: {table: owners}
+`)
+	type transfer struct {
+		From account   `json:"from"`
+		To   []account `json:"to"`
+	}
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name     string
+		validate func(ctx context.Context) ValidationErrors
+	}{
+		{"struct", func(ctx context.Context) ValidationErrors {
+			_, errs := v.ValidateStructContext(ctx, transfer{From: account{OwnerID: 1}, To: []account{{OwnerID: 1}}})
+			return errs
+		}},
+		{"map", func(ctx context.Context) ValidationErrors {
+			_, errs := v.ValidateMapContext(ctx, map[string]interface{}{
+				"from": map[string]interface{}{"owner_id": 1.0},
+				"to":   []interface{}{map[string]interface{}{"owner_id": 1.0}},
+			})
+			return errs
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if errs := tt.validate(context.Background()); len(errs) != 0 {
+				t.Fatalf("errors = %v, want none", errs)
+			}
+			// The nested checks run bound to the context of the caller
+			errs := tt.validate(canceled)
+			fields := []string{}
+			for _, err := range errs {
+				fields = append(fields, err.Field)
+			}
+			sort.Strings(fields)
+			if want := []string{"from.owner_id", "to[0].owner_id"}; !reflect.DeepEqual(fields, want) {
+				t.Fatalf("fields = %v, want %v: %v", fields, want, errs)
+			}
+			if err := errs.Err(); !errors.Is(err, context.Canceled) {
+				t.Fatalf("Err() = %v, want context.Canceled", err)
+			}
+		})
+	}
+}
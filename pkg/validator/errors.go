@@ -0,0 +1,181 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Error codes identify the kind of failure independently of the message
+const (
+	CodeInvalidInput = "invalid_input"
+	CodeRequired     = "required"
+	CodeType         = "type"
+	CodeInteger      = "integer"
+	CodeMinLength    = "min_length"
+	CodeMaxLength    = "max_length"
+	CodePattern      = "pattern"
+	CodeFormat       = "format"
+	CodeEnum         = "enum"
+	CodeMin          = "min"
+	CodeMax          = "max"
+	CodeCompare      = "compare"
+	CodeRequiredIf   = "required_if"
+	CodeRequiredWith = "required_with"
+	CodeExclusive    = "exclusive"
+	CodeUnique       = "unique"
+	CodeExists       = "exists"
+	CodeUnavailable  = "unavailable"
+)
+
+// ValidationError describes why a field was rejected
+type ValidationError struct {
+	// Field is the path of the field, like "address.city" or "tags[2]"
+	Field string `json:"field"`
+	// Rule is the schema key that failed, like "minLength"
+	Rule string `json:"rule"`
+	// Code identifies the failure, like "min_length"
+	Code string `json:"code"`
+	// Params are the values of the rule, like {"min": 3}
+	Params map[string]interface{} `json:"params,omitempty"`
+	// Message is the human readable description of the failure
+	Message string `json:"message"`
+	// template is the message set by the schema, overriding the catalogs
+	// in the locales it has a text for
+	template LocalizedMessage
+	// fallback is the message used when no catalog has one for the error
+	fallback string
+	// cause is the failure of the query of an unavailable error
+	cause error
+}
+
+func (e ValidationError) Error() string {
+	return e.Message
+}
+
+// ValidationErrors are the errors of a failed validation
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	return strings.Join(e.Strings(), "; ")
+}
+
+// Strings returns the messages of the errors, as validations used to report them
+func (e ValidationErrors) Strings() []string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Message
+	}
+	return messages
+}
+
+// Err returns the failure of the first rule that could not be checked
+// against the database, if any. Such errors are not the fault of the client
+// and should be reported as internal errors rather than validation failures.
+func (e ValidationErrors) Err() error {
+	for _, err := range e {
+		if err.cause != nil {
+			return err.cause
+		}
+	}
+	return nil
+}
+
+// Localize returns the errors with their messages in the given locale,
+// falling back to DefaultLocale for messages missing in its catalog
+func (e ValidationErrors) Localize(locale string) ValidationErrors {
+	errors := make(ValidationErrors, len(e))
+	for i, err := range e {
+		err.Message = err.render(locale)
+		errors[i] = err
+	}
+	return errors
+}
+
+// Prefixed returns the errors of a nested object with their fields under
+// parent, like "skills[0].name"
+func (e ValidationErrors) Prefixed(parent string) ValidationErrors {
+	errors := make(ValidationErrors, len(e))
+	for i, err := range e {
+		err.Field = parent + "." + err.Field
+		err.Message = err.render(DefaultLocale)
+		errors[i] = err
+	}
+	return errors
+}
+
+// withMessages applies the messages a schema sets on rule to the errors of field
+func (e ValidationErrors) withMessages(field string, rule *ValidationRule) ValidationErrors {
+	if len(rule.Message) == 0 {
+		return e
+	}
+	for i, err := range e {
+		if err.Field != field {
+			continue
+		}
+		if template, ok := rule.Message.lookup(err); ok {
+			err.template = template
+			err.Message = err.render(DefaultLocale)
+			e[i] = err
+		}
+	}
+	return e
+}
+
+// render formats the message of the error in the given locale
+func (e ValidationError) render(locale string) string {
+	template, found := e.template.text(locale)
+	if !found {
+		keys := []string{messageKey(e.Code, e.Params), e.Code}
+		if e.Rule == "custom" {
+			keys = []string{"custom." + e.Code}
+		}
+		if template, found = lookupMessage(locale, keys...); !found && e.fallback != "" {
+			template = e.fallback
+		}
+	}
+	return formatMessage(template, e.Field, e.Params)
+}
+
+// newError builds a validation error and its message
+func newError(field, rule, code string, params map[string]interface{}) ValidationError {
+	err := ValidationError{
+		Field:  field,
+		Rule:   rule,
+		Code:   code,
+		Params: params,
+	}
+	err.Message = err.render(DefaultLocale)
+	return err
+}
+
+// typeError reports a value that is not of the expected type
+func typeError(field, typ string) ValidationError {
+	return newError(field, "type", CodeType, map[string]interface{}{"type": typ})
+}
+
+// messageKey returns the key of the message template of an error, type,
+// format and compare errors are keyed by "<code>.<type|format|operator>"
+func messageKey(code string, params map[string]interface{}) string {
+	switch code {
+	case CodeType:
+		return fmt.Sprintf("%s.%v", code, params["type"])
+	case CodeFormat:
+		return fmt.Sprintf("%s.%v", code, params["format"])
+	case CodeCompare:
+		return fmt.Sprintf("%s.%v", code, params["operator"])
+	case CodeRequiredIf:
+		if _, ok := params["value"]; ok {
+			return code + ".value"
+		}
+	}
+	return code
+}
+
+// formatMessage fills the placeholders of a message template
+func formatMessage(template, field string, params map[string]interface{}) string {
+	msg := strings.ReplaceAll(template, "{field}", field)
+	for key, value := range params {
+		msg = strings.ReplaceAll(msg, "{"+key+"}", fmt.Sprintf("%v", value))
+	}
+	return msg
+}
@@ -0,0 +1,150 @@
+package validator
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// mustValidator loads a validator from a YAML schema
+func mustValidator(t *testing.T, schema string) *StructValidator {
+	t.Helper()
+	v := NewStructValidator()
+	if err := v.LoadSchemaFromYAML(schema); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+// person is the struct the validator tests run against
+type person struct {
+	Name  string   `json:"name"`
+	Age   int      `json:"age"`
+	Email *string  `json:"email"`
+	Tags  []string `json:"tags"`
+	Role  string   `json:"role"`
+}
+
+const personSchema = `
+name: Person
+rules:
+  - field: name
+    type: string
+    required: true
+    minLength: 3
+    maxLength: 5
+    pattern: "^[a-z]+$"
+  - field: age
+    type: integer
+    min: 18
+    max: 99
+  - field: email
+    type: string
+    required: true
+  - field: tags
+    type: array
+    items:
+      field: tag
+      type: string
+      maxLength: 2
+  - field: role
+    type: string
+    enum: [admin, user]
+`
+
+// withoutMessages strips the messages of errs, sorted by field and code
+func withoutMessages(errs ValidationErrors) ValidationErrors {
+	result := ValidationErrors{}
+	for _, err := range errs {
+		result = append(result, ValidationError{Field: err.Field, Rule: err.Rule, Code: err.Code, Params: err.Params})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Field+result[i].Code < result[j].Field+result[j].Code
+	})
+	return result
+}
+
+func TestValidationErrors(t *testing.T) {
+	v := mustValidator(t, personSchema)
+	email := "ana@example.com"
+
+	tests := []struct {
+		name string
+		data person
+		want ValidationErrors
+	}{
+		{"valid", person{Name: "ana", Age: 30, Email: &email, Role: "user"}, ValidationErrors{}},
+		{"required", person{Name: "ana", Age: 30, Role: "user"}, ValidationErrors{
+			{Field: "email", Rule: "required", Code: CodeRequired},
+		}},
+		{"lengths and pattern", person{Name: "Anabella", Age: 30, Email: &email, Role: "user"}, ValidationErrors{
+			{Field: "name", Rule: "maxLength", Code: CodeMaxLength, Params: map[string]interface{}{"max": 5}},
+			{Field: "name", Rule: "pattern", Code: CodePattern, Params: map[string]interface{}{"pattern": "^[a-z]+$"}},
+		}},
+		{"min length", person{Name: "al", Age: 30, Email: &email, Role: "user"}, ValidationErrors{
+			{Field: "name", Rule: "minLength", Code: CodeMinLength, Params: map[string]interface{}{"min": 3}},
+		}},
+		{"bounds", person{Name: "ana", Age: 12, Email: &email, Role: "user"}, ValidationErrors{
+			{Field: "age", Rule: "min", Code: CodeMin, Params: map[string]interface{}{"min": 18.0}},
+		}},
+		{"enum and items", person{Name: "ana", Age: 30, Email: &email, Tags: []string{"ok", "long"}, Role: "root"}, ValidationErrors{
+			{Field: "role", Rule: "enum", Code: CodeEnum, Params: map[string]interface{}{"values": []interface{}{"admin", "user"}}},
+			{Field: "tags[1]", Rule: "maxLength", Code: CodeMaxLength, Params: map[string]interface{}{"max": 2}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid, errs := v.ValidateStruct(tt.data)
+			if valid != (len(tt.want) == 0) {
+				t.Fatalf("valid = %v, errors %v", valid, errs)
+			}
+			if got := withoutMessages(errs); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidationErrorsOfMaps(t *testing.T) {
+	v := mustValidator(t, personSchema)
+
+	valid, errs := v.ValidateMap(map[string]interface{}{"name": 3, "age": 20.5, "email": "a@b.c", "tags": "x"})
+	if valid {
+		t.Fatal("invalid map accepted")
+	}
+	want := ValidationErrors{
+		{Field: "age", Rule: "type", Code: CodeInteger},
+		{Field: "name", Rule: "type", Code: CodeType, Params: map[string]interface{}{"type": "string"}},
+		{Field: "tags", Rule: "type", Code: CodeType, Params: map[string]interface{}{"type": "array"}},
+	}
+	if got := withoutMessages(errs); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %#v, want %#v", got, want)
+	}
+}
+
+func TestValidationErrorMessages(t *testing.T) {
+	errs := ValidationErrors{
+		newError("name", "required", CodeRequired, nil),
+		newError("name", "minLength", CodeMinLength, map[string]interface{}{"min": 3}),
+		typeError("age", "number"),
+	}
+	want := []string{
+		"Field 'name' is required",
+		"Field 'name' must have at least 3 characters",
+		"Field 'age' must be a number",
+	}
+	if got := errs.Strings(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Strings() = %q, want %q", got, want)
+	}
+	if got := errs.Error(); got != want[0]+"; "+want[1]+"; "+want[2] {
+		t.Fatalf("Error() = %q", got)
+	}
+
+	prefixed := errs[:1].Prefixed("skills[0]")
+	if prefixed[0].Field != "skills[0].name" || prefixed[0].Message != "Field 'skills[0].name' is required" {
+		t.Fatalf("Prefixed = %+v", prefixed[0])
+	}
+	if errs[0].Field != "name" {
+		t.Fatal("Prefixed changed the original errors")
+	}
+}
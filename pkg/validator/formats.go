@@ -0,0 +1,88 @@
+package validator
+
+import (
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FormatFunc reports whether a string is valid for a format
+type FormatFunc func(value string) bool
+
+var (
+	uuidPattern  = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	phonePattern = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+)
+
+// formats are the string formats usable with the format key of a rule
+var formats = map[string]FormatFunc{
+	// email is an RFC 5322 address without display name, like "ana@example.com"
+	"email": func(value string) bool {
+		addr, err := mail.ParseAddress(value)
+		return err == nil && addr.Address == value
+	},
+	// uuid is an RFC 4122 UUID in its canonical textual form
+	"uuid": func(value string) bool {
+		return uuidPattern.MatchString(value)
+	},
+	// url is an absolute URL with scheme and host
+	"url": func(value string) bool {
+		u, err := url.Parse(value)
+		return err == nil && u.Scheme != "" && u.Host != ""
+	},
+	// date is an RFC 3339 full-date, like "2024-01-15"
+	"date": func(value string) bool {
+		_, err := time.Parse(time.DateOnly, value)
+		return err == nil
+	},
+	// date-time is an RFC 3339 date-time, like "2024-01-15T10:30:00Z"
+	"date-time": func(value string) bool {
+		_, err := time.Parse(time.RFC3339, value)
+		return err == nil
+	},
+	"ipv4": func(value string) bool {
+		ip := net.ParseIP(value)
+		return ip != nil && ip.To4() != nil && !strings.Contains(value, ":")
+	},
+	"ipv6": func(value string) bool {
+		return net.ParseIP(value) != nil && strings.Contains(value, ":")
+	},
+	// phone is an E.164 number, like "+5491164880417"
+	"phone": func(value string) bool {
+		return phonePattern.MatchString(value)
+	},
+}
+
+// formatsMu guards formats, RegisterFormat may run while requests are
+// validated
+var formatsMu sync.RWMutex
+
+// RegisterFormat adds or replaces a format usable with the format key of a rule
+func RegisterFormat(name string, fn FormatFunc) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	formats[name] = fn
+}
+
+// lookupFormat returns the function of a registered format
+func lookupFormat(name string) (FormatFunc, bool) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	fn, ok := formats[name]
+	return fn, ok
+}
+
+// formatError returns the error of a string not valid for the format of rule
+func formatError(fieldName, str string, rule *ValidationRule) (ValidationError, bool) {
+	if rule.Format == "" {
+		return ValidationError{}, false
+	}
+	if fn, ok := lookupFormat(rule.Format); ok && fn(str) {
+		return ValidationError{}, false
+	}
+	return newError(fieldName, "format", CodeFormat, map[string]interface{}{"format": rule.Format}), true
+}
@@ -0,0 +1,117 @@
+package validator
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestFormats(t *testing.T) {
+	tests := []struct {
+		format string
+		value  string
+		valid  bool
+	}{
+		{"email", "ana@example.com", true},
+		{"email", "ana.perez+tag@mail.example.com", true},
+		{"email", "Ana <ana@example.com>", false},
+		{"email", "ana.example.com", false},
+		{"email", "", false},
+		{"uuid", "123e4567-e89b-12d3-a456-426614174000", true},
+		{"uuid", "123E4567-E89B-12D3-A456-426614174000", true},
+		{"uuid", "123e4567e89b12d3a456426614174000", false},
+		{"uuid", "123e4567-e89b-12d3-a456-42661417400z", false},
+		{"url", "https://example.com/path?q=1", true},
+		{"url", "ftp://files.example.com", true},
+		{"url", "example.com", false},
+		{"url", "/path", false},
+		{"url", "mailto:ana@example.com", false},
+		{"date", "2024-01-15", true},
+		{"date", "2024-02-30", false},
+		{"date", "15/01/2024", false},
+		{"date", "2024-01-15T10:30:00Z", false},
+		{"date-time", "2024-01-15T10:30:00Z", true},
+		{"date-time", "2024-01-15T10:30:00.5-03:00", true},
+		{"date-time", "2024-01-15 10:30:00", false},
+		{"date-time", "2024-01-15", false},
+		{"ipv4", "192.168.0.1", true},
+		{"ipv4", "256.1.1.1", false},
+		{"ipv4", "::ffff:192.168.0.1", false},
+		{"ipv4", "2001:db8::1", false},
+		{"ipv6", "2001:db8::1", true},
+		{"ipv6", "::1", true},
+		{"ipv6", "192.168.0.1", false},
+		{"ipv6", "2001:db8::g", false},
+		{"phone", "+5491164880417", true},
+		{"phone", "+12", true},
+		{"phone", "5491164880417", false},
+		{"phone", "+0123456", false},
+		{"phone", "+54 9 11 6488", false},
+		{"phone", "+1234567890123456", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format+" "+tt.value, func(t *testing.T) {
+			err, failed := formatError("field", tt.value, &ValidationRule{Format: tt.format})
+			if failed == tt.valid {
+				t.Fatalf("valid = %v, want %v", !failed, tt.valid)
+			}
+			if failed && (err.Code != CodeFormat || err.Params["format"] != tt.format) {
+				t.Fatalf("error = %+v", err)
+			}
+		})
+	}
+}
+
+func TestRegisterFormat(t *testing.T) {
+	t.Cleanup(func() {
+		formatsMu.Lock()
+		delete(formats, "slug")
+		formatsMu.Unlock()
+	})
+
+	// Unknown formats are rejected when the schema is loaded
+	schema := `
+name: Post
+rules:
+  - field: slug
+    type: string
+    format: slug
+`
+	if err := NewStructValidator().LoadSchemaFromYAML(schema); err == nil {
+		t.Fatal("unknown format accepted")
+	}
+
+	RegisterFormat("slug", func(value string) bool {
+		return value != "" && strings.Trim(value, "abcdefghijklmnopqrstuvwxyz0123456789-") == ""
+	})
+	v := mustValidator(t, schema)
+	if valid, errs := v.ValidateMap(map[string]interface{}{"slug": "hello-world"}); !valid {
+		t.Errorf("valid slug rejected: %v", errs)
+	}
+	valid, errs := v.ValidateMap(map[string]interface{}{"slug": "Hello World"})
+	if valid || len(errs) != 1 || errs[0].Code != CodeFormat {
+		t.Errorf("invalid slug: valid = %v, errors %v", valid, errs)
+	}
+}
+
+func TestRegisterFormatConcurrently(t *testing.T) {
+	t.Cleanup(func() {
+		formatsMu.Lock()
+		delete(formats, "even")
+		formatsMu.Unlock()
+	})
+	rule := &ValidationRule{Format: "email"}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			RegisterFormat("even", func(value string) bool { return len(value)%2 == 0 })
+		}()
+		go func() {
+			defer wg.Done()
+			formatError("email", "ana@example.com", rule)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,301 @@
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// JSONSchemaDialect is the JSON Schema version produced and read by the converters
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema is the subset of JSON Schema draft 2020-12 matching the
+// validation rules. unique, exists and custom rules, which have no JSON
+// Schema counterpart, are kept in x- keywords so schemas round trip.
+type JSONSchema struct {
+	Schema            string                 `json:"$schema,omitempty"`
+	Ref               string                 `json:"$ref,omitempty"`
+	Defs              map[string]*JSONSchema `json:"$defs,omitempty"`
+	Title             string                 `json:"title,omitempty"`
+	Description       string                 `json:"description,omitempty"`
+	Type              interface{}            `json:"type,omitempty"`
+	Properties        map[string]*JSONSchema `json:"properties,omitempty"`
+	Required          []string               `json:"required,omitempty"`
+	DependentRequired map[string][]string    `json:"dependentRequired,omitempty"`
+	Items             *JSONSchema            `json:"items,omitempty"`
+	Minimum           *float64               `json:"minimum,omitempty"`
+	Maximum           *float64               `json:"maximum,omitempty"`
+	MinLength         *int                   `json:"minLength,omitempty"`
+	MaxLength         *int                   `json:"maxLength,omitempty"`
+	Pattern           string                 `json:"pattern,omitempty"`
+	Format            string                 `json:"format,omitempty"`
+	Enum              []interface{}          `json:"enum,omitempty"`
+	Const             interface{}            `json:"const,omitempty"`
+	Unique            bool                   `json:"x-unique,omitempty"`
+	Exists            *ExistsRule            `json:"x-exists,omitempty"`
+	Custom            []CustomRule           `json:"x-custom,omitempty"`
+}
+
+// jsonSchemaTypes maps rule types to JSON Schema types
+var jsonSchemaTypes = map[string]string{
+	"string":  "string",
+	"number":  "number",
+	"integer": "integer",
+	"boolean": "boolean",
+	"array":   "array",
+	"slice":   "array",
+	"object":  "object",
+	"struct":  "object",
+}
+
+// jsonSchemaFormats maps the formats whose JSON Schema name differs
+var jsonSchemaFormats = map[string]string{
+	"url": "uri",
+}
+
+// ToJSONSchema converts a validation schema to a JSON Schema describing an
+// object. requiredWith constraints become dependentRequired, the other
+// constraints cannot be expressed and are left out.
+func ToJSONSchema(s *ValidationSchema) *JSONSchema {
+	js := objectSchema(s)
+	js.Schema = JSONSchemaDialect
+	return js
+}
+
+func objectSchema(s *ValidationSchema) *JSONSchema {
+	js := &JSONSchema{
+		Title:       s.Name,
+		Description: s.Description,
+		Type:        "object",
+		Properties:  map[string]*JSONSchema{},
+	}
+	for _, rule := range s.Rules {
+		js.Properties[rule.FieldName] = ruleSchema(rule)
+		if rule.Required {
+			js.Required = append(js.Required, rule.FieldName)
+		}
+	}
+	for _, c := range s.Constraints {
+		if c.Type != ConstraintRequiredWith {
+			continue
+		}
+		if js.DependentRequired == nil {
+			js.DependentRequired = map[string][]string{}
+		}
+		for _, field := range c.Fields {
+			js.DependentRequired[field] = append(js.DependentRequired[field], c.Field)
+		}
+	}
+	return js
+}
+
+func ruleSchema(rule *ValidationRule) *JSONSchema {
+	js := &JSONSchema{
+		Description: rule.Description,
+		Minimum:     rule.Min,
+		Maximum:     rule.Max,
+		MinLength:   rule.MinLength,
+		MaxLength:   rule.MaxLength,
+		Pattern:     rule.Pattern,
+		Format:      rule.Format,
+		Enum:        rule.Enum,
+		Unique:      rule.Unique,
+		Exists:      rule.Exists,
+		Custom:      rule.Custom,
+	}
+	if typ, ok := jsonSchemaTypes[rule.Type]; ok {
+		js.Type = typ
+	}
+	if format, ok := jsonSchemaFormats[rule.Format]; ok {
+		js.Format = format
+	}
+	if rule.Nested != nil {
+		nested := objectSchema(rule.Nested)
+		js.Properties = nested.Properties
+		js.Required = nested.Required
+		js.DependentRequired = nested.DependentRequired
+		js.Title = nested.Title
+	}
+	if rule.ArrayItems != nil {
+		js.Items = ruleSchema(rule.ArrayItems)
+	}
+	return js
+}
+
+// ParseJSONSchema decodes a JSON Schema document and converts it, see FromJSONSchema
+func ParseJSONSchema(data []byte) (*ValidationSchema, error) {
+	var js JSONSchema
+	if err := json.Unmarshal(data, &js); err != nil {
+		return nil, fmt.Errorf("error parsing JSON Schema: %v", err)
+	}
+	return FromJSONSchema(&js)
+}
+
+// FromJSONSchema converts a JSON Schema describing an object to a validation
+// schema. Local $ref to $defs are resolved, keywords without a rule
+// counterpart are ignored.
+func FromJSONSchema(js *JSONSchema) (*ValidationSchema, error) {
+	c := &schemaConverter{defs: js.Defs, resolving: map[string]bool{}}
+	return c.object(js)
+}
+
+// schemaConverter converts JSON Schemas sharing the same $defs
+type schemaConverter struct {
+	defs      map[string]*JSONSchema
+	resolving map[string]bool
+}
+
+func (c *schemaConverter) resolve(js *JSONSchema) (*JSONSchema, error) {
+	if js.Ref == "" {
+		return js, nil
+	}
+	name := strings.TrimPrefix(js.Ref, "#/$defs/")
+	def, ok := c.defs[name]
+	if !ok || name == js.Ref {
+		return nil, fmt.Errorf("unsupported $ref %s", js.Ref)
+	}
+	if c.resolving[name] {
+		return nil, fmt.Errorf("recursive $ref %s", js.Ref)
+	}
+	return def, nil
+}
+
+func (c *schemaConverter) object(js *JSONSchema) (*ValidationSchema, error) {
+	js, err := c.resolve(js)
+	if err != nil {
+		return nil, err
+	}
+	if typ := schemaType(js.Type); typ != "" && typ != "object" {
+		return nil, fmt.Errorf("schema must describe an object, got %s", typ)
+	}
+
+	s := &ValidationSchema{Name: js.Title, Description: js.Description}
+	required := map[string]bool{}
+	for _, name := range js.Required {
+		required[name] = true
+	}
+
+	names := make([]string, 0, len(js.Properties))
+	for name := range js.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		rule, err := c.rule(name, js.Properties[name])
+		if err != nil {
+			return nil, err
+		}
+		rule.Required = required[name]
+		s.Rules = append(s.Rules, rule)
+	}
+
+	triggers := make([]string, 0, len(js.DependentRequired))
+	for trigger := range js.DependentRequired {
+		triggers = append(triggers, trigger)
+	}
+	sort.Strings(triggers)
+	for _, trigger := range triggers {
+		for _, field := range js.DependentRequired[trigger] {
+			s.Constraints = append(s.Constraints, &Constraint{
+				Type:   ConstraintRequiredWith,
+				Field:  field,
+				Fields: []string{trigger},
+			})
+		}
+	}
+	return s, nil
+}
+
+func (c *schemaConverter) rule(name string, js *JSONSchema) (*ValidationRule, error) {
+	if js.Ref != "" {
+		def, err := c.resolve(js)
+		if err != nil {
+			return nil, err
+		}
+		ref := strings.TrimPrefix(js.Ref, "#/$defs/")
+		c.resolving[ref] = true
+		defer delete(c.resolving, ref)
+		js = def
+	}
+
+	rule := &ValidationRule{
+		FieldName:   name,
+		Type:        schemaType(js.Type),
+		Description: js.Description,
+		Min:         js.Minimum,
+		Max:         js.Maximum,
+		MinLength:   js.MinLength,
+		MaxLength:   js.MaxLength,
+		Pattern:     js.Pattern,
+		Format:      js.Format,
+		Enum:        js.Enum,
+		Unique:      js.Unique,
+		Exists:      js.Exists,
+		Custom:      js.Custom,
+	}
+	if js.Const != nil {
+		rule.Enum = []interface{}{js.Const}
+	}
+	for format, jsFormat := range jsonSchemaFormats {
+		if js.Format == jsFormat {
+			rule.Format = format
+		}
+	}
+	// Formats unknown to the validator are annotations in JSON Schema
+	if _, ok := lookupFormat(rule.Format); !ok {
+		rule.Format = ""
+	}
+
+	if rule.Type == "" && js.Properties != nil {
+		rule.Type = "object"
+	}
+	if rule.Type == "object" && js.Properties != nil {
+		nested, err := c.object(&JSONSchema{
+			Title:             js.Title,
+			Properties:        js.Properties,
+			Required:          js.Required,
+			DependentRequired: js.DependentRequired,
+		})
+		if err != nil {
+			return nil, err
+		}
+		rule.Nested = nested
+	}
+	if rule.Type == "array" && js.Items != nil {
+		items, err := c.rule(name, js.Items)
+		if err != nil {
+			return nil, err
+		}
+		rule.ArrayItems = items
+	}
+	return rule, nil
+}
+
+// schemaType returns the rule type of a JSON Schema type, which may be a
+// list like ["string", "null"]
+func schemaType(typ interface{}) string {
+	switch t := typ.(type) {
+	case string:
+		if t == "null" {
+			return ""
+		}
+		return t
+	case []interface{}:
+		for _, item := range t {
+			if s, ok := item.(string); ok && s != "null" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// LoadSchemaFromJSONSchema loads validation rules from a JSON Schema document
+func (v *StructValidator) LoadSchemaFromJSONSchema(content string) error {
+	schema, err := ParseJSONSchema([]byte(content))
+	if err != nil {
+		return err
+	}
+	return v.LoadSchema(schema)
+}
@@ -0,0 +1,283 @@
+package validator
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const profileSchema = `
+name: Profile
+description: A user profile
+rules:
+  - field: address
+    type: object
+    required: true
+    nested:
+      name: Address
+      rules:
+        - field: city
+          type: string
+          required: true
+          maxLength: 30
+  - field: age
+    type: integer
+    min: 18
+    max: 120
+  - field: city
+    type: string
+  - field: email
+    type: string
+    required: true
+    format: email
+    unique: true
+  - field: owner_id
+    type: integer
+    exists: {table: internal_user}
+  - field: role
+    type: string
+    enum: [admin, user]
+  - field: tags
+    type: array
+    items:
+      field: tags
+      type: string
+      pattern: "^[a-z]+$"
+  - field: website
+    type: string
+    format: url
+    custom: [reachable]
+  - field: zip
+    type: string
+    minLength: 4
+constraints:
+  - type: requiredWith
+    field: zip
+    fields: [city]
+  - type: compare
+    field: age
+    operator: gt
+    other: owner_id
+`
+
+// jsonEqual reports whether two JSON documents hold the same values
+func jsonEqual(t *testing.T, a, b []byte) bool {
+	t.Helper()
+	var x, y interface{}
+	if err := json.Unmarshal(a, &x); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &y); err != nil {
+		t.Fatal(err)
+	}
+	return reflect.DeepEqual(x, y)
+}
+
+// withReachable registers the custom rule of profileSchema for the test
+func withReachable(t *testing.T) {
+	RegisterRule("reachable", func(value interface{}, params map[string]interface{}) error { return nil })
+	t.Cleanup(func() {
+		customRulesMu.Lock()
+		delete(customRules, "reachable")
+		customRulesMu.Unlock()
+	})
+}
+
+func TestToJSONSchema(t *testing.T) {
+	withReachable(t)
+	v := mustValidator(t, profileSchema)
+	got, err := json.Marshal(ToJSONSchema(v.GetSchema()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The compare constraint has no JSON Schema counterpart
+	want := `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title": "Profile",
+		"description": "A user profile",
+		"type": "object",
+		"required": ["address", "email"],
+		"dependentRequired": {"city": ["zip"]},
+		"properties": {
+			"address": {"type": "object", "title": "Address", "required": ["city"], "properties": {
+				"city": {"type": "string", "maxLength": 30}
+			}},
+			"age": {"type": "integer", "minimum": 18, "maximum": 120},
+			"city": {"type": "string"},
+			"email": {"type": "string", "format": "email", "x-unique": true},
+			"owner_id": {"type": "integer", "x-exists": {"table": "internal_user"}},
+			"role": {"type": "string", "enum": ["admin", "user"]},
+			"tags": {"type": "array", "items": {"type": "string", "pattern": "^[a-z]+$"}},
+			"website": {"type": "string", "format": "uri", "x-custom": [{"name": "reachable"}]},
+			"zip": {"type": "string", "minLength": 4}
+		}
+	}`
+	if !jsonEqual(t, got, []byte(want)) {
+		t.Fatalf("got %s", got)
+	}
+}
+
+func TestFromJSONSchema(t *testing.T) {
+	schema, err := ParseJSONSchema([]byte(`{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title": "Order",
+		"type": "object",
+		"required": ["id", "customer"],
+		"dependentRequired": {"coupon": ["discount", "campaign"]},
+		"$defs": {
+			"customer": {"type": "object", "required": ["name"], "properties": {
+				"name": {"type": "string", "minLength": 1},
+				"site": {"type": "string", "format": "uri"}
+			}}
+		},
+		"properties": {
+			"id": {"type": "string", "format": "uuid"},
+			"customer": {"$ref": "#/$defs/customer"},
+			"kind": {"const": "retail"},
+			"note": {"type": ["string", "null"], "maxLength": 200},
+			"host": {"type": "string", "format": "hostname"},
+			"lines": {"type": "array", "items": {"type": "object", "properties": {"qty": {"type": "integer", "minimum": 1}}}},
+			"coupon": {"type": "string"},
+			"discount": {"type": "number"},
+			"campaign": {"type": "string"}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := SchemaToYAML(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Properties are sorted, unknown formats like hostname are dropped and
+	// const becomes a single value enum
+	want := `name: Order
+description: ""
+rules:
+  - field: campaign
+    type: string
+  - field: coupon
+    type: string
+  - field: customer
+    type: object
+    required: true
+    nested:
+      name: ""
+      description: ""
+      rules:
+        - field: name
+          type: string
+          required: true
+          minLength: 1
+        - field: site
+          type: string
+          format: url
+  - field: discount
+    type: number
+  - field: host
+    type: string
+  - field: id
+    type: string
+    required: true
+    format: uuid
+  - field: kind
+    type: ""
+    enum:
+      - retail
+  - field: lines
+    type: array
+    items:
+      field: lines
+      type: object
+      nested:
+        name: ""
+        description: ""
+        rules:
+          - field: qty
+            type: integer
+            min: 1
+  - field: note
+    type: string
+    maxLength: 200
+constraints:
+  - type: requiredWith
+    field: discount
+    fields:
+      - coupon
+  - type: requiredWith
+    field: campaign
+    fields:
+      - coupon
+`
+	if string(got) != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+
+	v := NewStructValidator()
+	if err := v.LoadSchema(schema); err != nil {
+		t.Fatal(err)
+	}
+	_, errs := v.ValidateMap(map[string]interface{}{"id": "x", "customer": map[string]interface{}{}, "coupon": "SALE"})
+	fields := []string{}
+	for _, err := range withoutMessages(errs) {
+		fields = append(fields, err.Field+":"+err.Code)
+	}
+	wantFields := "campaign:required_with,customer.name:required,discount:required_with,id:format"
+	if strings.Join(fields, ",") != wantFields {
+		t.Fatalf("errors = %v, want %s", fields, wantFields)
+	}
+}
+
+func TestFromJSONSchemaErrors(t *testing.T) {
+	tests := []struct {
+		schema string
+		err    string
+	}{
+		{`{"type": "array"}`, "must describe an object"},
+		{`{"properties": {"a": {"$ref": "https://example.com/a.json"}}}`, "unsupported $ref"},
+		{`{"properties": {"a": {"$ref": "#/$defs/missing"}}}`, "unsupported $ref"},
+		{`{"$defs": {"node": {"type": "object", "properties": {"child": {"$ref": "#/$defs/node"}}}},
+			"properties": {"root": {"$ref": "#/$defs/node"}}}`, "recursive $ref"},
+		{`{"properties": `, "error parsing JSON Schema"},
+	}
+	for _, tt := range tests {
+		if _, err := ParseJSONSchema([]byte(tt.schema)); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: err = %v, want %s", tt.schema, err, tt.err)
+		}
+	}
+
+	// A $ref on the root object is resolved
+	schema, err := ParseJSONSchema([]byte(`{"$ref": "#/$defs/item", "$defs": {"item": {"type": "object", "properties": {"a": {"type": "string"}}}}}`))
+	if err != nil || len(schema.Rules) != 1 || schema.Rules[0].FieldName != "a" {
+		t.Fatalf("root $ref: %+v, %v", schema, err)
+	}
+}
+
+func TestJSONSchemaRoundTrip(t *testing.T) {
+	withReachable(t)
+	v := mustValidator(t, profileSchema)
+	data, err := json.Marshal(ToJSONSchema(v.GetSchema()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := ParseJSONSchema(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Everything but the compare constraint survives the round trip
+	original := *v.GetSchema()
+	original.Constraints = original.Constraints[:1]
+	want, err := SchemaToYAML(&original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := SchemaToYAML(back)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+}
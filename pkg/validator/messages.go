@@ -0,0 +1,258 @@
+package validator
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultLocale is the locale of the messages returned by the validations
+var DefaultLocale = "en"
+
+// catalogs hold the message templates by locale and key, see messageKey.
+// {field} and {param} placeholders are replaced with the field and the
+// params of the error.
+var catalogs = map[string]map[string]string{
+	"en": {
+		CodeInvalidInput:    "Input must be a struct",
+		CodeRequired:        "Field '{field}' is required",
+		"type.string":       "Field '{field}' must be a string",
+		"type.number":       "Field '{field}' must be a number",
+		"type.boolean":      "Field '{field}' must be a boolean",
+		"type.array":        "Field '{field}' must be an array",
+		"type.object":       "Field '{field}' must be an object",
+		CodeInteger:         "Field '{field}' must be an integer",
+		CodeMinLength:       "Field '{field}' must have at least {min} characters",
+		CodeMaxLength:       "Field '{field}' must have at most {max} characters",
+		CodePattern:         "Field '{field}' must match pattern {pattern}",
+		CodeFormat:          "Field '{field}' must be a valid {format}",
+		"format.email":      "Field '{field}' must be a valid email address",
+		"format.uuid":       "Field '{field}' must be a valid UUID",
+		"format.url":        "Field '{field}' must be a valid URL",
+		"format.date":       "Field '{field}' must be a date like 2006-01-02",
+		"format.date-time":  "Field '{field}' must be a date and time like 2006-01-02T15:04:05Z",
+		"format.ipv4":       "Field '{field}' must be a valid IPv4 address",
+		"format.ipv6":       "Field '{field}' must be a valid IPv6 address",
+		"format.phone":      "Field '{field}' must be a phone number like +5491112345678",
+		CodeEnum:            "Field '{field}' must be one of {values}",
+		CodeMin:             "Field '{field}' must be >= {min}",
+		CodeMax:             "Field '{field}' must be <= {max}",
+		"compare.eq":        "Field '{field}' must be equal to '{other}'",
+		"compare.ne":        "Field '{field}' must be different from '{other}'",
+		"compare.gt":        "Field '{field}' must be greater than '{other}'",
+		"compare.gte":       "Field '{field}' must be greater than or equal to '{other}'",
+		"compare.lt":        "Field '{field}' must be less than '{other}'",
+		"compare.lte":       "Field '{field}' must be less than or equal to '{other}'",
+		CodeRequiredIf:      "Field '{field}' is required when '{other}' is set",
+		"required_if.value": "Field '{field}' is required when '{other}' is {value}",
+		CodeRequiredWith:    "Field '{field}' is required when any of {fields} is set",
+		CodeExclusive:       "Only one of {fields} can be set",
+		CodeUnique:          "Field '{field}' is already taken",
+		CodeExists:          "Field '{field}' must reference an existing {table}",
+		CodeUnavailable:     "Field '{field}' could not be checked",
+	},
+	"es": {
+		CodeInvalidInput:    "La entrada debe ser una estructura",
+		CodeRequired:        "El campo '{field}' es obligatorio",
+		"type.string":       "El campo '{field}' debe ser un texto",
+		"type.number":       "El campo '{field}' debe ser un número",
+		"type.boolean":      "El campo '{field}' debe ser un booleano",
+		"type.array":        "El campo '{field}' debe ser una lista",
+		"type.object":       "El campo '{field}' debe ser un objeto",
+		CodeInteger:         "El campo '{field}' debe ser un número entero",
+		CodeMinLength:       "El campo '{field}' debe tener al menos {min} caracteres",
+		CodeMaxLength:       "El campo '{field}' debe tener como máximo {max} caracteres",
+		CodePattern:         "El campo '{field}' debe coincidir con el patrón {pattern}",
+		CodeFormat:          "El campo '{field}' debe tener formato {format}",
+		"format.email":      "El campo '{field}' debe ser un email válido",
+		"format.uuid":       "El campo '{field}' debe ser un UUID válido",
+		"format.url":        "El campo '{field}' debe ser una URL válida",
+		"format.date":       "El campo '{field}' debe ser una fecha como 2006-01-02",
+		"format.date-time":  "El campo '{field}' debe ser una fecha y hora como 2006-01-02T15:04:05Z",
+		"format.ipv4":       "El campo '{field}' debe ser una dirección IPv4 válida",
+		"format.ipv6":       "El campo '{field}' debe ser una dirección IPv6 válida",
+		"format.phone":      "El campo '{field}' debe ser un teléfono como +5491112345678",
+		CodeEnum:            "El campo '{field}' debe ser uno de {values}",
+		CodeMin:             "El campo '{field}' debe ser >= {min}",
+		CodeMax:             "El campo '{field}' debe ser <= {max}",
+		"compare.eq":        "El campo '{field}' debe ser igual a '{other}'",
+		"compare.ne":        "El campo '{field}' debe ser distinto de '{other}'",
+		"compare.gt":        "El campo '{field}' debe ser mayor que '{other}'",
+		"compare.gte":       "El campo '{field}' debe ser mayor o igual que '{other}'",
+		"compare.lt":        "El campo '{field}' debe ser menor que '{other}'",
+		"compare.lte":       "El campo '{field}' debe ser menor o igual que '{other}'",
+		CodeRequiredIf:      "El campo '{field}' es obligatorio cuando '{other}' está presente",
+		"required_if.value": "El campo '{field}' es obligatorio cuando '{other}' es {value}",
+		CodeRequiredWith:    "El campo '{field}' es obligatorio cuando alguno de {fields} está presente",
+		CodeExclusive:       "Solo uno de {fields} puede estar presente",
+		CodeUnique:          "El valor del campo '{field}' ya está en uso",
+		CodeExists:          "El campo '{field}' debe referenciar un {table} existente",
+		CodeUnavailable:     "No se pudo verificar el campo '{field}'",
+	},
+}
+
+// catalogsMu guards catalogs, RegisterMessages may run while requests
+// render messages
+var catalogsMu sync.RWMutex
+
+// Messages overrides the error messages of a rule by error code. In YAML it
+// is either a map of codes to messages or a single message used for every
+// code, and each message is either a text used for every locale or a map of
+// texts by locale, e.g.
+//
+//	message: "El usuario solo admite letras, números y _"
+//	message:
+//	  pattern:
+//	    en: "Field '{field}' may only contain letters, digits and _"
+//	    es: "El campo '{field}' solo admite letras, números y _"
+type Messages map[string]LocalizedMessage
+
+// LocalizedMessage holds the texts of a message by locale, the text under
+// the empty locale is used for every locale
+type LocalizedMessage map[string]string
+
+// UnmarshalYAML accepts a single message as well as a map of messages
+func (m *Messages) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*m = Messages{"*": {"": node.Value}}
+		return nil
+	}
+	raw := map[string]LocalizedMessage{}
+	if err := node.Decode(&raw); err != nil {
+		return err
+	}
+	*m = raw
+	return nil
+}
+
+// UnmarshalYAML accepts a single text as well as a map of texts by locale
+func (m *LocalizedMessage) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*m = LocalizedMessage{"": node.Value}
+		return nil
+	}
+	raw := map[string]string{}
+	if err := node.Decode(&raw); err != nil {
+		return err
+	}
+	*m = LocalizedMessage{}
+	for locale, text := range raw {
+		(*m)[normalizeLocale(locale)] = text
+	}
+	return nil
+}
+
+// MarshalYAML writes a message used for every locale as a single text
+func (m LocalizedMessage) MarshalYAML() (interface{}, error) {
+	if text, ok := m[""]; ok && len(m) == 1 {
+		return text, nil
+	}
+	return map[string]string(m), nil
+}
+
+// lookup returns the message set for the error, if any
+func (m Messages) lookup(err ValidationError) (LocalizedMessage, bool) {
+	for _, key := range []string{messageKey(err.Code, err.Params), err.Code, "*"} {
+		if msg, ok := m[key]; ok {
+			return msg, true
+		}
+	}
+	return nil, false
+}
+
+// text returns the text of the message in locale, its base language or for
+// every locale, in that order
+func (m LocalizedMessage) text(locale string) (string, bool) {
+	locale = normalizeLocale(locale)
+	base, _, _ := strings.Cut(locale, "-")
+	for _, key := range []string{locale, base, ""} {
+		if text, ok := m[key]; ok {
+			return text, true
+		}
+	}
+	return "", false
+}
+
+// normalizeLocale spells a locale as catalogs are keyed, "pt_BR" as "pt-br"
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+}
+
+// RegisterMessages adds or replaces message templates of a locale, new
+// locales become available to MatchLocale. The locale is matched regardless
+// of case and of "_" or "-" as separator.
+func RegisterMessages(locale string, messages map[string]string) {
+	locale = normalizeLocale(locale)
+	catalogsMu.Lock()
+	defer catalogsMu.Unlock()
+	catalog, ok := catalogs[locale]
+	if !ok {
+		catalog = map[string]string{}
+		catalogs[locale] = catalog
+	}
+	for key, msg := range messages {
+		catalog[key] = msg
+	}
+}
+
+// Locales returns the locales with a message catalog
+func Locales() []string {
+	catalogsMu.RLock()
+	defer catalogsMu.RUnlock()
+	locales := make([]string, 0, len(catalogs))
+	for locale := range catalogs {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// MatchLocale picks the best locale with a catalog for an Accept-Language
+// header like "es-AR,es;q=0.9,en;q=0.8", DefaultLocale when none matches
+func MatchLocale(acceptLanguage string) string {
+	catalogsMu.RLock()
+	defer catalogsMu.RUnlock()
+	best, bestQ := DefaultLocale, 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, q := strings.TrimSpace(part), 1.0
+		if i := strings.Index(tag, ";"); i >= 0 {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(tag[i+1:]), "q="); ok {
+				if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+					q = parsed
+				}
+			}
+			tag = strings.TrimSpace(tag[:i])
+		}
+		tag = normalizeLocale(tag)
+		if q <= bestQ {
+			continue
+		}
+		for _, candidate := range []string{tag, strings.Split(tag, "-")[0]} {
+			if _, ok := catalogs[candidate]; ok {
+				best, bestQ = candidate, q
+				break
+			}
+		}
+	}
+	return best
+}
+
+// lookupMessage returns the template of the first key found in locale,
+// falling back to the region-less locale and then to DefaultLocale. The
+// first key is returned when none is found.
+func lookupMessage(locale string, keys ...string) (string, bool) {
+	locale = normalizeLocale(locale)
+	catalogsMu.RLock()
+	defer catalogsMu.RUnlock()
+	for _, candidate := range []string{locale, strings.Split(locale, "-")[0], DefaultLocale} {
+		for _, key := range keys {
+			if msg, ok := catalogs[candidate][key]; ok {
+				return msg, true
+			}
+		}
+	}
+	return keys[0], false
+}
@@ -0,0 +1,197 @@
+package validator
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestMatchLocale(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "en"},
+		{"es", "es"},
+		{"ES", "es"},
+		{"es-AR", "es"},
+		{"es_AR", "es"},
+		{"es-AR,es;q=0.9,en;q=0.8", "es"},
+		{"en;q=0.2, es;q=0.8", "es"},
+		{"es;q=0.5,en", "en"},
+		{"fr-FR, en;q=0.5", "en"},
+		{"fr-FR,fr;q=0.9", "en"},
+		{"de, es;q=0.1", "es"},
+		{"es;q=0", "en"},
+		{"es;q=abc", "es"},
+		{"*", "en"},
+		{" , ;q=1", "en"},
+	}
+	for _, tt := range tests {
+		if got := MatchLocale(tt.header); got != tt.want {
+			t.Errorf("MatchLocale(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestRegisterMessages(t *testing.T) {
+	t.Cleanup(func() {
+		catalogsMu.Lock()
+		delete(catalogs, "pt")
+		catalogsMu.Unlock()
+	})
+	RegisterMessages("pt", map[string]string{CodeRequired: "O campo '{field}' é obrigatório"})
+
+	if got := MatchLocale("pt-BR,en;q=0.5"); got != "pt" {
+		t.Fatalf("MatchLocale = %q, want pt", got)
+	}
+	errs := ValidationErrors{
+		newError("name", "required", CodeRequired, nil),
+		newError("name", "minLength", CodeMinLength, map[string]interface{}{"min": 3}),
+	}.Localize("pt-BR")
+	if got, want := errs[0].Message, "O campo 'name' é obrigatório"; got != want {
+		t.Errorf("registered message = %q, want %q", got, want)
+	}
+	// Messages missing in the catalog fall back to DefaultLocale
+	if got, want := errs[1].Message, "Field 'name' must have at least 3 characters"; got != want {
+		t.Errorf("fallback message = %q, want %q", got, want)
+	}
+}
+
+func TestRegisterMessagesMixedCase(t *testing.T) {
+	t.Cleanup(func() {
+		catalogsMu.Lock()
+		delete(catalogs, "es-ar")
+		delete(catalogs, "pt-br")
+		catalogsMu.Unlock()
+	})
+	RegisterMessages("es-AR", map[string]string{CodeRequired: "Che, el campo '{field}' es obligatorio"})
+	RegisterMessages("pt_BR", map[string]string{CodeRequired: "O campo '{field}' é obrigatório"})
+
+	tests := []struct {
+		header string
+		want   string
+		msg    string
+	}{
+		{"es-AR", "es-ar", "Che, el campo 'name' es obligatorio"},
+		{"ES_ar", "es-ar", "Che, el campo 'name' es obligatorio"},
+		{"pt-BR", "pt-br", "O campo 'name' é obrigatório"},
+		{"pt_br", "pt-br", "O campo 'name' é obrigatório"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			got := MatchLocale(tt.header)
+			if got != tt.want {
+				t.Fatalf("MatchLocale(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+			errs := ValidationErrors{newError("name", "required", CodeRequired, nil)}.Localize(tt.header)
+			if errs[0].Message != tt.msg {
+				t.Errorf("message = %q, want %q", errs[0].Message, tt.msg)
+			}
+		})
+	}
+}
+
+func TestRegisterMessagesConcurrently(t *testing.T) {
+	t.Cleanup(func() {
+		catalogsMu.Lock()
+		delete(catalogs, "it")
+		catalogsMu.Unlock()
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			RegisterMessages("it", map[string]string{CodeRequired: "Il campo '{field}' è obbligatorio"})
+		}()
+		go func() {
+			defer wg.Done()
+			MatchLocale("it,es;q=0.5")
+			ValidationErrors{newError("name", "required", CodeRequired, nil)}.Localize("it")
+		}()
+	}
+	wg.Wait()
+}
+
+func TestSchemaMessages(t *testing.T) {
+	v := mustValidator(t, `
+name: Account
+rules:
+  - field: username
+    type: string
+    pattern: "^[a-z]+$"
+    message:
+      pattern:
+        en: "Field '{field}' may only contain letters"
+        es: "El campo '{field}' solo admite letras"
+  - field: code
+    type: string
+    minLength: 3
+    message:
+      min_length:
+        es: "El código es corto"
+        pt_BR: "O código é curto"
+  - field: name
+    type: string
+    required: true
+    message: "Name is mandatory"
+`)
+	_, errs := v.ValidateMap(map[string]interface{}{"username": "Ana1", "code": "x"})
+
+	tests := []struct {
+		locale string
+		want   map[string]string
+	}{
+		{"en", map[string]string{
+			"username": "Field 'username' may only contain letters",
+			"code":     "Field 'code' must have at least 3 characters",
+			"name":     "Name is mandatory",
+		}},
+		// Overrides without a text for the locale fall back to the catalogs
+		{"es-AR", map[string]string{
+			"username": "El campo 'username' solo admite letras",
+			"code":     "El código es corto",
+			"name":     "Name is mandatory",
+		}},
+		// Locales of the schema are matched regardless of case and separator
+		{"pt-br", map[string]string{
+			"username": "Field 'username' must match pattern ^[a-z]+$",
+			"code":     "O código é curto",
+			"name":     "Name is mandatory",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			got := map[string]string{}
+			for _, err := range errs.Localize(tt.locale) {
+				got[err.Field] = err.Message
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for field, msg := range tt.want {
+				if got[field] != msg {
+					t.Errorf("%s: message = %q, want %q", field, got[field], msg)
+				}
+			}
+		})
+	}
+}
+
+func TestShippedSchemaMessages(t *testing.T) {
+	v := NewStructValidator()
+	if err := v.LoadSchemaFromFile("../../schemas/internal_users.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	_, errs := v.ValidateMap(map[string]interface{}{"username": "ana-1", "email": "ana@example.com"})
+	want := map[string]string{
+		"en": "Field 'username' may only contain letters, digits and _",
+		"es": "El campo 'username' solo admite letras, números y _",
+	}
+	for locale, msg := range want {
+		localized := errs.Localize(locale)
+		if len(localized) != 1 || localized[0].Message != msg {
+			t.Errorf("%s: errors = %v, want %q", locale, localized, msg)
+		}
+	}
+}
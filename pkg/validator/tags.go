@@ -0,0 +1,270 @@
+package validator
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm/schema"
+)
+
+// SchemaFromStruct builds a validation schema from the validate tags of a
+// model, e.g.
+//
+//	Username string `json:"username" validate:"required,min=3,max=20,pattern=^[a-zA-Z0-9_]+$"`
+//
+// Rules are named after the json tag of the field. min and max bound the
+// length of strings and the value of numbers, pattern commas are escaped as
+// \, and any registered format can be used as a key, like email. Other keys
+// are required, minLength, maxLength, format, enum=a|b, unique,
+// exists=table[.column] and custom=name. The gorm tag adds required for
+// "not null" and maxLength for "size". Fields of struct types, embedded ones
+// included, are validated with the tags of their own fields, other fields
+// without tags are skipped. So are the associations declared with a gorm
+// foreignKey, references or many2many tag, which are validated on their own.
+func SchemaFromStruct(model interface{}) (*ValidationSchema, error) {
+	typ := reflect.TypeOf(model)
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("model must be a struct, got %T", model)
+	}
+	return schemaFromType(typ, map[reflect.Type]bool{})
+}
+
+func schemaFromType(typ reflect.Type, seen map[reflect.Type]bool) (*ValidationSchema, error) {
+	s := &ValidationSchema{
+		Name:        typ.Name(),
+		Description: "Generated from the tags of " + typ.Name(),
+	}
+	seen[typ] = true
+	defer delete(seen, typ)
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+
+		// Embedded structs contribute their own fields, even unexported ones
+		// as their fields are promoted
+		if field.Anonymous && indirect(field.Type).Kind() == reflect.Struct {
+			embedded, err := schemaFromType(indirect(field.Type), seen)
+			if err != nil {
+				return nil, err
+			}
+			s.Rules = append(s.Rules, embedded.Rules...)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		rule, err := ruleFromField(field, seen)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", typ.Name(), field.Name, err)
+		}
+		if rule != nil {
+			s.Rules = append(s.Rules, rule)
+		}
+	}
+	return s, nil
+}
+
+// isAssociation reports whether the parsed gorm tag of a field declares an
+// association to another model
+func isAssociation(gormTag map[string]string) bool {
+	for _, key := range []string{"FOREIGNKEY", "REFERENCES", "MANY2MANY"} {
+		if _, ok := gormTag[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleFromField builds the rule of a struct field, nil when neither the field
+// nor the fields of its struct type have tags
+func ruleFromField(field reflect.StructField, seen map[reflect.Type]bool) (*ValidationRule, error) {
+	validateTag, hasValidate := field.Tag.Lookup("validate")
+	gormTag := schema.ParseTagSetting(field.Tag.Get("gorm"), ";")
+	_, notNull := gormTag["NOT NULL"]
+	size := gormTag["SIZE"]
+	if validateTag == "-" {
+		return nil, nil
+	}
+	if !hasValidate && isAssociation(gormTag) {
+		return nil, nil
+	}
+	hasTags := hasValidate || notNull || size != ""
+
+	name := field.Name
+	if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag == "-" {
+		return nil, nil
+	} else if tag != "" {
+		name = tag
+	}
+
+	rule := &ValidationRule{FieldName: name, Type: typeName(field.Type), Required: notNull}
+	if size != "" && rule.Type == "string" {
+		n, err := strconv.Atoi(size)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gorm size %q", size)
+		}
+		rule.MaxLength = &n
+	}
+
+	for _, option := range splitTag(validateTag) {
+		key, value, _ := strings.Cut(option, "=")
+		if err := applyTagOption(rule, key, value); err != nil {
+			return nil, err
+		}
+	}
+
+	// Struct values are validated with the tags of their type
+	elem := indirect(field.Type)
+	switch {
+	case rule.Type == "object" && elem.Kind() == reflect.Struct && !seen[elem]:
+		nested, err := schemaFromType(elem, seen)
+		if err != nil {
+			return nil, err
+		}
+		if len(nested.Rules) > 0 {
+			rule.Nested = nested
+		}
+	case rule.Type == "array" && indirect(elem.Elem()).Kind() == reflect.Struct && !seen[indirect(elem.Elem())]:
+		nested, err := schemaFromType(indirect(elem.Elem()), seen)
+		if err != nil {
+			return nil, err
+		}
+		if len(nested.Rules) > 0 {
+			rule.ArrayItems = &ValidationRule{FieldName: name, Type: "object", Nested: nested}
+		}
+	}
+	if !hasTags && rule.Nested == nil && rule.ArrayItems == nil {
+		return nil, nil
+	}
+	return rule, nil
+}
+
+// applyTagOption sets the part of rule described by a key=value tag option
+func applyTagOption(rule *ValidationRule, key, value string) error {
+	switch key {
+	case "":
+	case "required":
+		rule.Required = true
+	case "min", "max":
+		if rule.Type == "string" {
+			return applyTagOption(rule, key+"Length", value)
+		}
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q", key, value)
+		}
+		if key == "min" {
+			rule.Min = &n
+		} else {
+			rule.Max = &n
+		}
+	case "minLength", "maxLength":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q", key, value)
+		}
+		if key == "minLength" {
+			rule.MinLength = &n
+		} else {
+			rule.MaxLength = &n
+		}
+	case "pattern":
+		rule.Pattern = value
+	case "format":
+		rule.Format = value
+	case "enum":
+		for _, allowed := range strings.Split(value, "|") {
+			rule.Enum = append(rule.Enum, allowed)
+		}
+	case "unique":
+		rule.Unique = true
+	case "exists":
+		table, column, _ := strings.Cut(value, ".")
+		rule.Exists = &ExistsRule{Table: table, Column: column}
+	case "custom":
+		rule.Custom = append(rule.Custom, CustomRule{Name: value})
+	default:
+		if _, ok := lookupFormat(key); ok && value == "" {
+			rule.Format = key
+			return nil
+		}
+		return fmt.Errorf("unknown validate option %q", key)
+	}
+	return nil
+}
+
+// splitTag splits a validate tag on the commas not escaped as \,
+func splitTag(tag string) []string {
+	options := []string{}
+	var current strings.Builder
+	for i := 0; i < len(tag); i++ {
+		switch {
+		case tag[i] == '\\' && i+1 < len(tag) && tag[i+1] == ',':
+			current.WriteByte(',')
+			i++
+		case tag[i] == ',':
+			options = append(options, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(tag[i])
+		}
+	}
+	return append(options, current.String())
+}
+
+// typeName returns the rule type matching a Go type
+func typeName(typ reflect.Type) string {
+	typ = indirect(typ)
+	switch typ.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Struct:
+		if typ == reflect.TypeOf(time.Time{}) {
+			return ""
+		}
+		return "object"
+	case reflect.Map:
+		return "object"
+	}
+	return ""
+}
+
+// indirect returns the type pointed to by typ, typ itself if not a pointer
+func indirect(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ
+}
+
+// SchemaToYAML encodes a schema in the format read by LoadSchemaFromYAML
+func SchemaToYAML(s *ValidationSchema) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(s); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
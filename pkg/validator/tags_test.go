@@ -0,0 +1,241 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+)
+
+// address is nested in the tagged structs
+type address struct {
+	City string `json:"city" validate:"required,max=30"`
+	Zip  string `json:"zip" validate:"pattern=^[0-9]{4}$"`
+}
+
+// audit is embedded in the tagged structs
+type audit struct {
+	Author string `json:"author" gorm:"size:40"`
+}
+
+// member is the model the tag tests run against, like the models of an
+// application validated from their tags rather than from schema files
+type member struct {
+	audit
+	ID       int64     `json:"id" gorm:"primaryKey"`
+	Username string    `json:"username" validate:"required,min=3,max=20,pattern=^[a-zA-Z0-9_]+$,unique"`
+	Email    *string   `json:"email" validate:"required,email"`
+	Age      int       `json:"age" validate:"min=18,max=120"`
+	Score    float64   `json:"score" validate:"max=9.5"`
+	Role     string    `json:"role" gorm:"not null" validate:"enum=admin|user"`
+	Motto    string    `json:"motto" validate:"pattern=^[a-z]{1\\,3}$"`
+	TeamID   int64     `json:"team_id" validate:"exists=teams.code"`
+	Website  string    `json:"website" validate:"format=url,custom=reachable"`
+	Address  address   `json:"address" gorm:"-"`
+	Previous []address `json:"previous" gorm:"-"`
+	Secret   string    `json:"-" validate:"required"`
+	Notes    string    `json:"notes" validate:"-"`
+	Plain    string    `json:"plain"`
+}
+
+func TestSchemaFromStruct(t *testing.T) {
+	schema, err := SchemaFromStruct(&member{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := SchemaToYAML(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `name: member
+description: Generated from the tags of member
+rules:
+  - field: author
+    type: string
+    maxLength: 40
+  - field: username
+    type: string
+    required: true
+    minLength: 3
+    maxLength: 20
+    pattern: ^[a-zA-Z0-9_]+$
+    unique: true
+  - field: email
+    type: string
+    required: true
+    format: email
+  - field: age
+    type: integer
+    min: 18
+    max: 120
+  - field: score
+    type: number
+    max: 9.5
+  - field: role
+    type: string
+    required: true
+    enum:
+      - admin
+      - user
+  - field: motto
+    type: string
+    pattern: ^[a-z]{1,3}$
+  - field: team_id
+    type: integer
+    exists:
+      table: teams
+      column: code
+  - field: website
+    type: string
+    format: url
+    custom:
+      - name: reachable
+  - field: address
+    type: object
+    nested:
+      name: address
+      description: Generated from the tags of address
+      rules:
+        - field: city
+          type: string
+          required: true
+          maxLength: 30
+        - field: zip
+          type: string
+          pattern: ^[0-9]{4}$
+  - field: previous
+    type: array
+    items:
+      field: previous
+      type: object
+      nested:
+        name: address
+        description: Generated from the tags of address
+        rules:
+          - field: city
+            type: string
+            required: true
+            maxLength: 30
+          - field: zip
+            type: string
+            pattern: ^[0-9]{4}$
+`
+	if string(got) != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestValidateFromTags(t *testing.T) {
+	RegisterRule("reachable", func(value interface{}, params map[string]interface{}) error { return nil })
+	t.Cleanup(func() {
+		customRulesMu.Lock()
+		delete(customRules, "reachable")
+		customRulesMu.Unlock()
+	})
+	v := NewStructValidator()
+	if err := v.LoadSchemaFromStruct(member{}); err != nil {
+		t.Fatal(err)
+	}
+	email := "ana@example.com"
+	valid := member{Username: "ana", Email: &email, Age: 30, Role: "user", Motto: "go", Website: "https://example.com", Address: address{City: "Rosario", Zip: "2000"}}
+
+	tests := []struct {
+		name   string
+		edit   func(m *member)
+		fields []string
+	}{
+		{"valid", func(m *member) {}, nil},
+		{"string bounds", func(m *member) { m.Username = "a-" }, []string{"username", "username"}},
+		{"number bounds", func(m *member) { m.Age, m.Score = 12, 10 }, []string{"age", "score"}},
+		{"format and enum", func(m *member) { bad := "ana"; m.Email, m.Role = &bad, "root" }, []string{"email", "role"}},
+		{"escaped comma", func(m *member) { m.Motto = "abcd" }, []string{"motto"}},
+		{"nested", func(m *member) {
+			m.Address.City = strings.Repeat("x", 31)
+			m.Previous = []address{{City: "x", Zip: "12"}}
+		}, []string{"address.city", "previous[0].zip"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := valid
+			tt.edit(&m)
+			_, errs := v.ValidateStruct(m)
+			fields := []string{}
+			for _, err := range withoutMessages(errs) {
+				fields = append(fields, err.Field)
+			}
+			if strings.Join(fields, ",") != strings.Join(tt.fields, ",") {
+				t.Fatalf("fields = %v, want %v: %v", fields, tt.fields, errs)
+			}
+		})
+	}
+}
+
+func TestSchemaFromStructSkipsAssociations(t *testing.T) {
+	type team struct {
+		ID       int64    `json:"id" gorm:"primaryKey"`
+		Name     string   `json:"name" validate:"required"`
+		LeaderID int64    `json:"leader_id"`
+		Leader   member   `json:"leader" gorm:"foreignKey:LeaderID"`
+		Members  []member `json:"members" gorm:"foreignKey:TeamID;references:ID"`
+		Tagged   []member `json:"tagged" gorm:"many2many:team_tagged" validate:"required"`
+	}
+	schema, err := SchemaFromStruct(team{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := []string{}
+	for _, rule := range schema.Rules {
+		fields = append(fields, rule.FieldName)
+	}
+	// Associations are only validated when tagged themselves
+	if strings.Join(fields, ",") != "name,tagged" {
+		t.Fatalf("fields = %v, want name and tagged", fields)
+	}
+}
+
+func TestSchemaFromStructErrors(t *testing.T) {
+	tests := []struct {
+		model interface{}
+		err   string
+	}{
+		{"text", "model must be a struct"},
+		{struct {
+			Name string `validate:"shiny"`
+		}{}, `unknown validate option "shiny"`},
+		{struct {
+			Age int `validate:"min=ten"`
+		}{}, `invalid min "ten"`},
+		{struct {
+			Name string `validate:"minLength=1.5"`
+		}{}, `invalid minLength "1.5"`},
+		{struct {
+			Name string `gorm:"size:big"`
+		}{}, `invalid gorm size "big"`},
+	}
+	for _, tt := range tests {
+		if _, err := SchemaFromStruct(tt.model); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("SchemaFromStruct(%T) = %v, want %s", tt.model, err, tt.err)
+		}
+	}
+
+	// Schemas using unregistered custom rules are rejected when loaded
+	if err := NewStructValidator().LoadSchemaFromStruct(member{}); err == nil {
+		t.Error("unknown custom rule accepted")
+	}
+}
+
+func TestSplitTag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want []string
+	}{
+		{"", []string{""}},
+		{"required", []string{"required"}},
+		{"required,min=3", []string{"required", "min=3"}},
+		{`pattern=^a{1\,2}$,unique`, []string{"pattern=^a{1,2}$", "unique"}},
+		{`pattern=a\b`, []string{`pattern=a\b`}},
+	}
+	for _, tt := range tests {
+		if got := splitTag(tt.tag); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("splitTag(%q) = %q, want %q", tt.tag, got, tt.want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package validator
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"regexp"
@@ -14,23 +15,30 @@ import (
 type ValidationRule struct {
 	FieldName   string            `yaml:"field"`
 	Type        string            `yaml:"type"`
-	Required    bool              `yaml:"required"`
+	Required    bool              `yaml:"required,omitempty"`
 	Min         *float64          `yaml:"min,omitempty"`
 	Max         *float64          `yaml:"max,omitempty"`
 	MinLength   *int              `yaml:"minLength,omitempty"`
 	MaxLength   *int              `yaml:"maxLength,omitempty"`
 	Pattern     string            `yaml:"pattern,omitempty"`
+	Format      string            `yaml:"format,omitempty"`
+	Custom      []CustomRule      `yaml:"custom,omitempty"`
+	Unique      bool              `yaml:"unique,omitempty"`
+	Exists      *ExistsRule       `yaml:"exists,omitempty"`
 	Enum        []interface{}     `yaml:"enum,omitempty"`
 	Nested      *ValidationSchema `yaml:"nested,omitempty"`
 	ArrayItems  *ValidationRule   `yaml:"items,omitempty"`
 	Description string            `yaml:"description,omitempty"`
+	Message     Messages          `yaml:"message,omitempty"`
 }
 
 // ValidationSchema represents a collection of validation rules
 type ValidationSchema struct {
 	Name        string            `yaml:"name"`
 	Description string            `yaml:"description"`
+	Table       string            `yaml:"table,omitempty"`
 	Rules       []*ValidationRule `yaml:"rules"`
+	Constraints []*Constraint     `yaml:"constraints,omitempty"`
 }
 
 // StructValidator validates Go structs and maps against rules
@@ -54,7 +62,29 @@ func (v *StructValidator) LoadSchemaFromYAML(yamlContent string) error {
 		return fmt.Errorf("error parsing YAML: %v", err)
 	}
 
-	v.schema = &schema
+	return v.LoadSchema(&schema)
+}
+
+// LoadSchemaFromStruct loads validation rules from the tags of a model, see
+// SchemaFromStruct
+func (v *StructValidator) LoadSchemaFromStruct(model interface{}) error {
+	schema, err := SchemaFromStruct(model)
+	if err != nil {
+		return err
+	}
+	return v.LoadSchema(schema)
+}
+
+// LoadSchema checks and loads a validation schema
+func (v *StructValidator) LoadSchema(schema *ValidationSchema) error {
+	if err := checkRules(schema); err != nil {
+		return err
+	}
+	if err := checkConstraints(schema); err != nil {
+		return err
+	}
+
+	v.schema = schema
 
 	// Build rules map
 	v.rules = make(map[string]*ValidationRule)
@@ -65,6 +95,41 @@ func (v *StructValidator) LoadSchemaFromYAML(yamlContent string) error {
 	return nil
 }
 
+// checkRules reports the first rule of schema using an unknown format or custom rule
+func checkRules(schema *ValidationSchema) error {
+	for _, rule := range schema.Rules {
+		if err := checkRule(rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkRule(rule *ValidationRule) error {
+	if rule.Format != "" {
+		if _, ok := lookupFormat(rule.Format); !ok {
+			return fmt.Errorf("unknown format %s for field %s", rule.Format, rule.FieldName)
+		}
+	}
+	for _, custom := range rule.Custom {
+		if _, ok := lookupRule(custom.Name); !ok {
+			return fmt.Errorf("unknown custom rule %s for field %s", custom.Name, rule.FieldName)
+		}
+	}
+	if rule.Exists != nil && rule.Exists.Table == "" {
+		return fmt.Errorf("exists rule for field %s needs a table", rule.FieldName)
+	}
+	if rule.Nested != nil {
+		if err := checkRules(rule.Nested); err != nil {
+			return err
+		}
+	}
+	if rule.ArrayItems != nil {
+		return checkRule(rule.ArrayItems)
+	}
+	return nil
+}
+
 // LoadSchemaFromFile loads validation rules from a YAML file
 func (v *StructValidator) LoadSchemaFromFile(filepath string) error {
 	content, err := commons.ReadFile(filepath)
@@ -85,7 +150,18 @@ func (v *StructValidator) GetRules() map[string]*ValidationRule {
 }
 
 // ValidateStruct validates a Go struct against the rules
-func (v *StructValidator) ValidateStruct(data interface{}) (bool, []string) {
+func (v *StructValidator) ValidateStruct(data interface{}) (bool, ValidationErrors) {
+	return v.ValidateStructContext(context.Background(), data)
+}
+
+// ValidateStructContext is ValidateStruct running the database checks bound to ctx
+func (v *StructValidator) ValidateStructContext(ctx context.Context, data interface{}) (bool, ValidationErrors) {
+	return v.validateStruct(ctx, data, nil)
+}
+
+// validateStruct validates a Go struct against the rules of the given
+// fields, all of them when only is nil
+func (v *StructValidator) validateStruct(ctx context.Context, data interface{}, only map[string]bool) (bool, ValidationErrors) {
 	val := reflect.ValueOf(data)
 	typ := reflect.TypeOf(data)
 
@@ -96,19 +172,28 @@ func (v *StructValidator) ValidateStruct(data interface{}) (bool, []string) {
 	}
 
 	if val.Kind() != reflect.Struct {
-		return false, []string{"Input must be a struct"}
+		return false, ValidationErrors{newError("", "type", CodeInvalidInput, nil)}
 	}
 
-	errors := []string{}
+	errors := ValidationErrors{}
+
+	var rec *record
+	if v.hasDatabaseRules() {
+		rec = structRecord(val, typ)
+	}
 
 	// Check each rule
 	for fieldName, rule := range v.rules {
+		if only != nil && !only[fieldName] {
+			continue
+		}
+
 		// Find the field in the struct
 		fieldVal, _, found := v.findField(val, typ, fieldName)
 
 		// Check required fields
 		if rule.Required && !found {
-			errors = append(errors, fmt.Sprintf("Field '%s' is required", fieldName))
+			errors = append(errors, ValidationErrors{newError(fieldName, "required", CodeRequired, nil)}.withMessages(fieldName, rule)...)
 			continue
 		}
 
@@ -117,23 +202,51 @@ func (v *StructValidator) ValidateStruct(data interface{}) (bool, []string) {
 		}
 
 		// Validate the field value
-		fieldErrors := v.validateField(fieldName, fieldVal, rule)
-		errors = append(errors, fieldErrors...)
+		fieldErrors := v.validateField(ctx, fieldName, fieldVal, rule)
+
+		// Check the database only for otherwise valid values
+		if rec != nil && len(fieldErrors) == 0 && fieldVal.CanInterface() && !(fieldVal.Kind() == reflect.Ptr && fieldVal.IsNil()) {
+			fieldErrors = validateDatabase(ctx, fieldName, reflect.Indirect(fieldVal).Interface(), rule, rec)
+		}
+		errors = append(errors, fieldErrors.withMessages(fieldName, rule)...)
 	}
 
+	// Check the constraints between fields
+	errors = append(errors, v.validateConstraints(v.structLookup(val, typ), only)...)
+
 	return len(errors) == 0, errors
 }
 
 // ValidateMap validates a map[string]interface{} against the rules
-func (v *StructValidator) ValidateMap(data map[string]interface{}) (bool, []string) {
-	errors := []string{}
+func (v *StructValidator) ValidateMap(data map[string]interface{}) (bool, ValidationErrors) {
+	return v.ValidateMapContext(context.Background(), data)
+}
+
+// ValidateMapContext is ValidateMap running the database checks bound to ctx
+func (v *StructValidator) ValidateMapContext(ctx context.Context, data map[string]interface{}) (bool, ValidationErrors) {
+	return v.validateMap(ctx, data, nil)
+}
+
+// validateMap validates a map against the rules of the given fields, all of
+// them when only is nil
+func (v *StructValidator) validateMap(ctx context.Context, data map[string]interface{}, only map[string]bool) (bool, ValidationErrors) {
+	errors := ValidationErrors{}
+
+	var rec *record
+	if v.hasDatabaseRules() {
+		rec = v.mapRecord(data)
+	}
 
 	for fieldName, rule := range v.rules {
+		if only != nil && !only[fieldName] {
+			continue
+		}
+
 		value, exists := data[fieldName]
 
 		// Check required fields
 		if rule.Required && !exists {
-			errors = append(errors, fmt.Sprintf("Field '%s' is required", fieldName))
+			errors = append(errors, ValidationErrors{newError(fieldName, "required", CodeRequired, nil)}.withMessages(fieldName, rule)...)
 			continue
 		}
 
@@ -142,13 +255,53 @@ func (v *StructValidator) ValidateMap(data map[string]interface{}) (bool, []stri
 		}
 
 		// Validate the field value
-		fieldErrors := v.validateValue(fieldName, value, rule)
-		errors = append(errors, fieldErrors...)
+		fieldErrors := v.validateValue(ctx, fieldName, value, rule)
+
+		// Check the database only for otherwise valid values
+		if rec != nil && len(fieldErrors) == 0 {
+			fieldErrors = validateDatabase(ctx, fieldName, value, rule, rec)
+		}
+		errors = append(errors, fieldErrors.withMessages(fieldName, rule)...)
 	}
 
+	// Check the constraints between fields
+	errors = append(errors, v.validateConstraints(mapLookup(data), only)...)
+
 	return len(errors) == 0, errors
 }
 
+// ValidateStructPartial validates a Go struct against the rules of the given
+// fields only, as needed by partial updates. Constraints are checked when
+// they involve any of the fields.
+func (v *StructValidator) ValidateStructPartial(data interface{}, fields []string) (bool, ValidationErrors) {
+	return v.ValidateStructPartialContext(context.Background(), data, fields)
+}
+
+// ValidateStructPartialContext is ValidateStructPartial running the database
+// checks bound to ctx
+func (v *StructValidator) ValidateStructPartialContext(ctx context.Context, data interface{}, fields []string) (bool, ValidationErrors) {
+	only := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		only[field] = true
+	}
+	return v.validateStruct(ctx, data, only)
+}
+
+// ValidateMapPartial validates a map against the rules of the keys it holds only
+func (v *StructValidator) ValidateMapPartial(data map[string]interface{}) (bool, ValidationErrors) {
+	return v.ValidateMapPartialContext(context.Background(), data)
+}
+
+// ValidateMapPartialContext is ValidateMapPartial running the database checks
+// bound to ctx
+func (v *StructValidator) ValidateMapPartialContext(ctx context.Context, data map[string]interface{}) (bool, ValidationErrors) {
+	only := make(map[string]bool, len(data))
+	for field := range data {
+		only[field] = true
+	}
+	return v.validateMap(ctx, data, only)
+}
+
 // findField finds a field in a struct by name (case-insensitive)
 func (v *StructValidator) findField(val reflect.Value, typ reflect.Type, fieldName string) (reflect.Value, reflect.Type, bool) {
 	for i := 0; i < val.NumField(); i++ {
@@ -181,14 +334,14 @@ func (v *StructValidator) findField(val reflect.Value, typ reflect.Type, fieldNa
 	return reflect.Value{}, nil, false
 }
 
-// validateField validates a struct field
-func (v *StructValidator) validateField(fieldName string, fieldVal reflect.Value, rule *ValidationRule) []string {
-	errors := []string{}
+// validateField validates a struct field, checking nested objects with ctx
+func (v *StructValidator) validateField(ctx context.Context, fieldName string, fieldVal reflect.Value, rule *ValidationRule) ValidationErrors {
+	errors := ValidationErrors{}
 
 	// Handle zero values
 	if !fieldVal.IsValid() || (fieldVal.Kind() == reflect.Ptr && fieldVal.IsNil()) {
 		if rule.Required {
-			errors = append(errors, fmt.Sprintf("Field '%s' is required", fieldName))
+			errors = append(errors, newError(fieldName, "required", CodeRequired, nil))
 		}
 		return errors
 	}
@@ -202,7 +355,7 @@ func (v *StructValidator) validateField(fieldName string, fieldVal reflect.Value
 	switch rule.Type {
 	case "string":
 		if fieldVal.Kind() != reflect.String {
-			errors = append(errors, fmt.Sprintf("Field '%s' must be a string", fieldName))
+			errors = append(errors, typeError(fieldName, "string"))
 			return errors
 		}
 
@@ -210,16 +363,19 @@ func (v *StructValidator) validateField(fieldName string, fieldVal reflect.Value
 
 		// String constraints
 		if rule.MinLength != nil && len(str) < *rule.MinLength {
-			errors = append(errors, fmt.Sprintf("Field '%s' must have at least %d characters", fieldName, *rule.MinLength))
+			errors = append(errors, newError(fieldName, "minLength", CodeMinLength, map[string]interface{}{"min": *rule.MinLength}))
 		}
 		if rule.MaxLength != nil && len(str) > *rule.MaxLength {
-			errors = append(errors, fmt.Sprintf("Field '%s' must have at most %d characters", fieldName, *rule.MaxLength))
+			errors = append(errors, newError(fieldName, "maxLength", CodeMaxLength, map[string]interface{}{"max": *rule.MaxLength}))
 		}
 		if rule.Pattern != "" {
 			if matched, _ := regexp.MatchString(rule.Pattern, str); !matched {
-				errors = append(errors, fmt.Sprintf("Field '%s' must match pattern %s", fieldName, rule.Pattern))
+				errors = append(errors, newError(fieldName, "pattern", CodePattern, map[string]interface{}{"pattern": rule.Pattern}))
 			}
 		}
+		if err, failed := formatError(fieldName, str, rule); failed {
+			errors = append(errors, err)
+		}
 
 		// Enum validation
 		if len(rule.Enum) > 0 {
@@ -231,7 +387,7 @@ func (v *StructValidator) validateField(fieldName string, fieldVal reflect.Value
 				}
 			}
 			if !found {
-				errors = append(errors, fmt.Sprintf("Field '%s' must be one of %v", fieldName, rule.Enum))
+				errors = append(errors, newError(fieldName, "enum", CodeEnum, map[string]interface{}{"values": rule.Enum}))
 			}
 		}
 
@@ -245,45 +401,46 @@ func (v *StructValidator) validateField(fieldName string, fieldVal reflect.Value
 		case reflect.Float32, reflect.Float64:
 			num = fieldVal.Float()
 		default:
-			errors = append(errors, fmt.Sprintf("Field '%s' must be a number", fieldName))
+			errors = append(errors, typeError(fieldName, "number"))
 			return errors
 		}
 
 		// Integer check
 		if rule.Type == "integer" && num != float64(int(num)) {
-			errors = append(errors, fmt.Sprintf("Field '%s' must be an integer", fieldName))
+			errors = append(errors, newError(fieldName, "type", CodeInteger, nil))
 		}
 
 		// Number constraints
 		if rule.Min != nil && num < *rule.Min {
-			errors = append(errors, fmt.Sprintf("Field '%s' must be >= %v", fieldName, *rule.Min))
+			errors = append(errors, newError(fieldName, "min", CodeMin, map[string]interface{}{"min": *rule.Min}))
 		}
 		if rule.Max != nil && num > *rule.Max {
-			errors = append(errors, fmt.Sprintf("Field '%s' must be <= %v", fieldName, *rule.Max))
+			errors = append(errors, newError(fieldName, "max", CodeMax, map[string]interface{}{"max": *rule.Max}))
 		}
 
 	case "boolean":
 		if fieldVal.Kind() != reflect.Bool {
-			errors = append(errors, fmt.Sprintf("Field '%s' must be a boolean", fieldName))
+			errors = append(errors, typeError(fieldName, "boolean"))
 		}
 
 	case "array", "slice":
 		if fieldVal.Kind() != reflect.Slice && fieldVal.Kind() != reflect.Array {
-			errors = append(errors, fmt.Sprintf("Field '%s' must be an array", fieldName))
+			errors = append(errors, typeError(fieldName, "array"))
 			return errors
 		}
 
 		// Validate array items if rule specified
 		if rule.ArrayItems != nil {
 			for i := 0; i < fieldVal.Len(); i++ {
-				itemErrors := v.validateField(fmt.Sprintf("%s[%d]", fieldName, i), fieldVal.Index(i), rule.ArrayItems)
+				itemName := fmt.Sprintf("%s[%d]", fieldName, i)
+				itemErrors := v.validateField(ctx, itemName, fieldVal.Index(i), rule.ArrayItems).withMessages(itemName, rule.ArrayItems)
 				errors = append(errors, itemErrors...)
 			}
 		}
 
 	case "object", "struct":
 		if fieldVal.Kind() != reflect.Struct && fieldVal.Kind() != reflect.Map {
-			errors = append(errors, fmt.Sprintf("Field '%s' must be an object", fieldName))
+			errors = append(errors, typeError(fieldName, "object"))
 			return errors
 		}
 
@@ -297,11 +454,9 @@ func (v *StructValidator) validateField(fieldName string, fieldVal reflect.Value
 			}
 
 			if fieldVal.Kind() == reflect.Struct {
-				valid, nestedErrors := nestedValidator.ValidateStruct(fieldVal.Interface())
+				valid, nestedErrors := nestedValidator.ValidateStructContext(ctx, fieldVal.Interface())
 				if !valid {
-					for _, err := range nestedErrors {
-						errors = append(errors, fmt.Sprintf("%s.%s", fieldName, err))
-					}
+					errors = append(errors, nestedErrors.Prefixed(fieldName)...)
 				}
 			} else if fieldVal.Kind() == reflect.Map {
 				// Convert to map[string]interface{}
@@ -309,81 +464,88 @@ func (v *StructValidator) validateField(fieldName string, fieldVal reflect.Value
 				for _, key := range fieldVal.MapKeys() {
 					m[key.String()] = fieldVal.MapIndex(key).Interface()
 				}
-				valid, nestedErrors := nestedValidator.ValidateMap(m)
+				valid, nestedErrors := nestedValidator.ValidateMapContext(ctx, m)
 				if !valid {
-					for _, err := range nestedErrors {
-						errors = append(errors, fmt.Sprintf("%s.%s", fieldName, err))
-					}
+					errors = append(errors, nestedErrors.Prefixed(fieldName)...)
 				}
 			}
 		}
 	}
 
+	// Custom rules
+	if fieldVal.CanInterface() {
+		errors = append(errors, validateCustom(fieldName, fieldVal.Interface(), rule)...)
+	}
+
 	return errors
 }
 
-// validateValue validates a value from a map
-func (v *StructValidator) validateValue(fieldName string, value interface{}, rule *ValidationRule) []string {
-	errors := []string{}
+// validateValue validates a value from a map, checking nested objects with ctx
+func (v *StructValidator) validateValue(ctx context.Context, fieldName string, value interface{}, rule *ValidationRule) ValidationErrors {
+	errors := ValidationErrors{}
 
 	// Type validation
 	switch rule.Type {
 	case "string":
 		str, ok := value.(string)
 		if !ok {
-			errors = append(errors, fmt.Sprintf("Field '%s' must be a string", fieldName))
+			errors = append(errors, typeError(fieldName, "string"))
 			return errors
 		}
 
 		// String constraints
 		if rule.MinLength != nil && len(str) < *rule.MinLength {
-			errors = append(errors, fmt.Sprintf("Field '%s' must have at least %d characters", fieldName, *rule.MinLength))
+			errors = append(errors, newError(fieldName, "minLength", CodeMinLength, map[string]interface{}{"min": *rule.MinLength}))
 		}
 		if rule.MaxLength != nil && len(str) > *rule.MaxLength {
-			errors = append(errors, fmt.Sprintf("Field '%s' must have at most %d characters", fieldName, *rule.MaxLength))
+			errors = append(errors, newError(fieldName, "maxLength", CodeMaxLength, map[string]interface{}{"max": *rule.MaxLength}))
 		}
 		if rule.Pattern != "" {
 			if matched, _ := regexp.MatchString(rule.Pattern, str); !matched {
-				errors = append(errors, fmt.Sprintf("Field '%s' must match pattern %s", fieldName, rule.Pattern))
+				errors = append(errors, newError(fieldName, "pattern", CodePattern, map[string]interface{}{"pattern": rule.Pattern}))
 			}
 		}
+		if err, failed := formatError(fieldName, str, rule); failed {
+			errors = append(errors, err)
+		}
 
 	case "number", "integer":
 		num, ok := toNumber(value)
 		if !ok {
-			errors = append(errors, fmt.Sprintf("Field '%s' must be a number", fieldName))
+			errors = append(errors, typeError(fieldName, "number"))
 			return errors
 		}
 
 		// Integer check
 		if rule.Type == "integer" && num != float64(int(num)) {
-			errors = append(errors, fmt.Sprintf("Field '%s' must be an integer", fieldName))
+			errors = append(errors, newError(fieldName, "type", CodeInteger, nil))
 		}
 
 		// Number constraints
 		if rule.Min != nil && num < *rule.Min {
-			errors = append(errors, fmt.Sprintf("Field '%s' must be >= %v", fieldName, *rule.Min))
+			errors = append(errors, newError(fieldName, "min", CodeMin, map[string]interface{}{"min": *rule.Min}))
 		}
 		if rule.Max != nil && num > *rule.Max {
-			errors = append(errors, fmt.Sprintf("Field '%s' must be <= %v", fieldName, *rule.Max))
+			errors = append(errors, newError(fieldName, "max", CodeMax, map[string]interface{}{"max": *rule.Max}))
 		}
 
 	case "boolean":
 		if _, ok := value.(bool); !ok {
-			errors = append(errors, fmt.Sprintf("Field '%s' must be a boolean", fieldName))
+			errors = append(errors, typeError(fieldName, "boolean"))
 		}
 
 	case "array", "slice":
 		arr, ok := value.([]interface{})
 		if !ok {
-			errors = append(errors, fmt.Sprintf("Field '%s' must be an array", fieldName))
+			errors = append(errors, typeError(fieldName, "array"))
 			return errors
 		}
 
 		// Validate array items if rule specified
 		if rule.ArrayItems != nil {
 			for i, item := range arr {
-				itemErrors := v.validateValue(fmt.Sprintf("%s[%d]", fieldName, i), item, rule.ArrayItems)
+				itemName := fmt.Sprintf("%s[%d]", fieldName, i)
+				itemErrors := v.validateValue(ctx, itemName, item, rule.ArrayItems).withMessages(itemName, rule.ArrayItems)
 				errors = append(errors, itemErrors...)
 			}
 		}
@@ -391,7 +553,7 @@ func (v *StructValidator) validateValue(fieldName string, value interface{}, rul
 	case "object", "struct":
 		obj, ok := value.(map[string]interface{})
 		if !ok {
-			errors = append(errors, fmt.Sprintf("Field '%s' must be an object", fieldName))
+			errors = append(errors, typeError(fieldName, "object"))
 			return errors
 		}
 
@@ -404,11 +566,9 @@ func (v *StructValidator) validateValue(fieldName string, value interface{}, rul
 				nestedValidator.rules[r.FieldName] = r
 			}
 
-			valid, nestedErrors := nestedValidator.ValidateMap(obj)
+			valid, nestedErrors := nestedValidator.ValidateMapContext(ctx, obj)
 			if !valid {
-				for _, err := range nestedErrors {
-					errors = append(errors, fmt.Sprintf("%s.%s", fieldName, err))
-				}
+				errors = append(errors, nestedErrors.Prefixed(fieldName)...)
 			}
 		}
 	}
@@ -423,10 +583,13 @@ func (v *StructValidator) validateValue(fieldName string, value interface{}, rul
 			}
 		}
 		if !found {
-			errors = append(errors, fmt.Sprintf("Field '%s' must be one of %v", fieldName, rule.Enum))
+			errors = append(errors, newError(fieldName, "enum", CodeEnum, map[string]interface{}{"values": rule.Enum}))
 		}
 	}
 
+	// Custom rules
+	errors = append(errors, validateCustom(fieldName, value, rule)...)
+
 	return errors
 }
 
@@ -1,34 +1,289 @@
 package web
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"strings"
 
 	"github.com/arturoeanton/go-struc2fiber/pkg/handlers"
+	"github.com/arturoeanton/go-struc2fiber/pkg/repositories"
 	"github.com/arturoeanton/go-struc2fiber/pkg/validator"
 	"github.com/gofiber/fiber/v2"
 )
 
-func RegisterCRUD[T any](app *fiber.App, resourceName string, model T, vals ...string) {
-	modelType := reflect.TypeOf(model)
+// Operation identifies one of the routes RegisterCRUD can expose
+type Operation string
 
-	// Auto-genera todas las rutas CRUD
-	handler := handlers.NewHandler[T]()
-	validator1 := validator.NewStructValidator()
-	validator1.LoadSchemaFromFile(vals[0])
+const (
+	OpList   Operation = "list"
+	OpGet    Operation = "get"
+	OpCreate Operation = "create"
+	OpUpdate Operation = "update"
+	OpPatch  Operation = "patch"
+	OpDelete Operation = "delete"
+	OpSchema Operation = "schema"
+	// The bulk operations are POST and PATCH /:resource/_bulk and DELETE /:resource?ids=
+	OpBulkCreate Operation = "bulk_create"
+	OpBulkUpdate Operation = "bulk_update"
+	OpBulkDelete Operation = "bulk_delete"
+)
+
+var (
+	// AllOperations exposes every single item CRUD route, the default. The
+	// bulk routes are opt-in, add BulkOperations to expose them too.
+	AllOperations = []Operation{OpList, OpGet, OpCreate, OpUpdate, OpPatch, OpDelete, OpSchema}
+	// BulkOperations exposes the bulk create, update and delete routes
+	BulkOperations = []Operation{OpBulkCreate, OpBulkUpdate, OpBulkDelete}
+	// ReadOnlyOperations exposes only the list and get routes
+	ReadOnlyOperations = []Operation{OpList, OpGet}
+)
+
+// CRUDOptions configures the routes registered for a resource
+type CRUDOptions struct {
+	// CreateSchema is the YAML schema used to validate POST bodies
+	CreateSchema string
+	// UpdateSchema is the YAML schema used to validate PUT and PATCH bodies, defaults to CreateSchema
+	UpdateSchema string
+	// SchemaFromTags builds the validators from the validate tags of the model
+	// when no schema file is given
+	SchemaFromTags bool
+	// Operations lists the routes to expose, AllOperations when empty
+	Operations []Operation
+	// Prefix is prepended to the resource path, e.g. "/api/v1"
+	Prefix string
+	// IDParam is the name of the route parameter holding the ID, "id" by default
+	IDParam string
+	// Middlewares are run before the handler of each operation
+	Middlewares map[Operation][]fiber.Handler
+	// Transactional runs each write request, middlewares included, in one
+	// transaction rolled back when the request fails
+	Transactional bool
+	// CursorField is the JSON field cursor listings are ordered by, the primary key by default
+	CursorField string
+	// Filterable lists the JSON fields clients can filter on, e.g. ?value[gte]=10
+	Filterable []string
+	// Sortable lists the JSON fields clients can sort by, e.g. ?sort=-value,name
+	Sortable []string
+	// Includes lists the associations clients can load with ?include=, as
+	// dotted paths of JSON names like "checkins_of_agent.apartment"
+	Includes []string
+	// Associations lists the has one and has many associations, by JSON name,
+	// written along with the items: the children sent replace the stored ones
+	Associations []string
+	// AssociationSchemas maps associations to the YAML schema validating their
+	// children, which are validated from their validate tags with SchemaFromTags
+	AssociationSchemas map[string]string
+	// ForeignKey is the JSON field of nested items referencing their parent,
+	// found from the associations of the parent by default
+	ForeignKey string
+	// ParentParam is the name of the route parameter holding the parent ID of
+	// nested items, ForeignKey by default
+	ParentParam string
+}
 
-	var validator2 *validator.StructValidator
-	validator2 = validator1
+// RegisterCRUD registers all CRUD routes for a resource. The optional vals are
+// the create schema and the update schema, in that order.
+func RegisterCRUD[T any](app fiber.Router, resourceName string, model T, vals ...string) {
+	opts := CRUDOptions{}
+	if len(vals) > 0 {
+		opts.CreateSchema = vals[0]
+	}
 	if len(vals) > 1 {
-		validator2 = validator.NewStructValidator()
-		validator2.LoadSchemaFromFile(vals[1])
+		opts.UpdateSchema = vals[1]
 	}
+	RegisterCRUDWithOptions(app, resourceName, model, opts)
+}
 
-	app.Get("/"+resourceName, handler.GetAll)
-	app.Get("/"+resourceName+"/:id", handler.GetByID)
-	app.Post("/"+resourceName, handler.FxCreate(validator1))
-	app.Put("/"+resourceName+"/:id", handler.FxUpdate(validator2))
-	app.Delete("/"+resourceName+"/:id", handler.DeleteByID)
+// RegisterCRUDWithOptions registers the CRUD routes of a resource as described by opts
+func RegisterCRUDWithOptions[T any](app fiber.Router, resourceName string, model T, opts CRUDOptions) {
+	opts = withDefaults(opts)
+	handler := handlers.NewHandler[T]()
+	handler.SetName(resourceName)
+	path := strings.TrimSuffix(opts.Prefix, "/") + "/" + resourceName
+	registerCRUD(app, handler, path, model, opts, &Resource{Name: resourceName})
+}
+
+// RegisterNestedCRUD registers the CRUD routes of the items of a resource
+// belonging to an item of a parent resource, like
+// /internal_users/:user_id/skills for the skills of a user. Every query is
+// restricted to the children of the parent in the path, a missing parent
+// being not found, and the foreign key is forced on the items written.
+func RegisterNestedCRUD[P, T any](app fiber.Router, parentName string, parent P, resourceName string, model T, opts CRUDOptions) {
+	opts = withDefaults(opts)
+	if opts.ForeignKey == "" {
+		foreignKey, err := repositories.ForeignKey[P, T](resourceName)
+		if err != nil {
+			panic(fmt.Sprintf("nesting %s under %s: %v", resourceName, parentName, err))
+		}
+		opts.ForeignKey = foreignKey
+	}
+	if opts.ParentParam == "" {
+		opts.ParentParam = opts.ForeignKey
+	}
+	if opts.ParentParam == opts.IDParam {
+		panic(fmt.Sprintf("nesting %s under %s: parent and item parameters are both %q", resourceName, parentName, opts.IDParam))
+	}
+
+	parents := repositories.NewRepository[P]()
+	handler := handlers.NewHandler[T]()
+	handler.SetName(resourceName)
+	err := handler.SetParent(parentName, opts.ParentParam, opts.ForeignKey, func(ctx context.Context, id interface{}) error {
+		_, err := parents.GetByIDContext(ctx, id)
+		return err
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	path := strings.TrimSuffix(opts.Prefix, "/") + "/" + parentName + "/:" + opts.ParentParam + "/" + resourceName
+	registerCRUD(app, handler, path, model, opts, &Resource{Name: resourceName, Parent: parentName, ParentParam: opts.ParentParam})
+}
+
+// withDefaults fills the options left empty
+func withDefaults(opts CRUDOptions) CRUDOptions {
+	if opts.IDParam == "" {
+		opts.IDParam = "id"
+	}
+	if len(opts.Operations) == 0 {
+		opts.Operations = AllOperations
+	}
+	if opts.UpdateSchema == "" {
+		opts.UpdateSchema = opts.CreateSchema
+	}
+	return opts
+}
+
+// registerCRUD registers the routes of handler under path and records the resource
+func registerCRUD[T any](app fiber.Router, handler *handlers.Handler[T], path string, model T, opts CRUDOptions, resource *Resource) {
+	modelType := reflect.TypeOf(model)
+	resourceName := handler.Name()
 
-	fmt.Printf("Registered CRUD routes for %s at /%s\n", modelType.Name(), resourceName)
+	handler.SetIDParam(opts.IDParam)
+	if opts.CursorField != "" {
+		if err := handler.SetCursorField(opts.CursorField); err != nil {
+			panic(err)
+		}
+	}
+	if err := handler.SetFilterable(opts.Filterable...); err != nil {
+		panic(err)
+	}
+	if err := handler.SetSortable(opts.Sortable...); err != nil {
+		panic(err)
+	}
+	if err := handler.SetIncludes(opts.Includes...); err != nil {
+		panic(err)
+	}
+	if len(opts.Associations) > 0 {
+		if err := handler.SetAssociations(associationValidators[T](opts)); err != nil {
+			panic(err)
+		}
+	}
+
+	createValidator := loadValidator(opts.CreateSchema)
+	if createValidator == nil && opts.SchemaFromTags {
+		createValidator = validator.NewStructValidator()
+		if err := createValidator.LoadSchemaFromStruct(model); err != nil {
+			panic(fmt.Sprintf("building schema of %s from tags: %v", modelType.Name(), err))
+		}
+	}
+	updateValidator := createValidator
+	if opts.UpdateSchema != opts.CreateSchema {
+		updateValidator = loadValidator(opts.UpdateSchema)
+	}
+
+	itemPath := path + "/:" + opts.IDParam
+
+	// The schema and bulk routes go first so their paths are not taken for an item ID
+	for _, op := range opts.Operations {
+		switch op {
+		case OpSchema:
+			app.Get(path+"/_schema", withMiddlewares(opts, op, handler.FxSchema(createValidator, updateValidator))...)
+		case OpBulkCreate:
+			app.Post(path+"/_bulk", withMiddlewares(opts, op, handler.FxBulkCreate(createValidator))...)
+		case OpBulkUpdate:
+			app.Patch(path+"/_bulk", withMiddlewares(opts, op, handler.FxBulkUpdate(updateValidator))...)
+		}
+	}
+
+	// Auto-genera las rutas CRUD habilitadas
+	for _, op := range opts.Operations {
+		switch op {
+		case OpSchema, OpBulkCreate, OpBulkUpdate:
+			// Registered above
+		case OpList:
+			app.Get(path, withMiddlewares(opts, op, handler.GetAll)...)
+		case OpGet:
+			app.Get(itemPath, withMiddlewares(opts, op, handler.GetByID)...)
+		case OpCreate:
+			app.Post(path, withMiddlewares(opts, op, handler.FxCreate(createValidator))...)
+		case OpUpdate:
+			app.Put(itemPath, withMiddlewares(opts, op, handler.FxUpdate(updateValidator))...)
+		case OpPatch:
+			app.Patch(itemPath, withMiddlewares(opts, op, handler.FxPatch(updateValidator))...)
+		case OpDelete:
+			app.Delete(itemPath, withMiddlewares(opts, op, handler.DeleteByID)...)
+		case OpBulkDelete:
+			app.Delete(path, withMiddlewares(opts, op, handler.DeleteByIDs)...)
+		default:
+			panic(fmt.Sprintf("unknown operation %q for %s", op, resourceName))
+		}
+	}
+
+	resource.Path = routerPrefix(app) + path
+	resource.IDParam = opts.IDParam
+	resource.Model = modelType
+	resource.Operations = opts.Operations
+	resource.CreateSchema = schemaOf(createValidator)
+	resource.UpdateSchema = schemaOf(updateValidator)
+	resource.Filterable = opts.Filterable
+	resource.Sortable = opts.Sortable
+	resource.Includes = opts.Includes
+	addResource(resource)
+
+	fmt.Printf("Registered CRUD routes for %s at %s %v\n", modelType.Name(), path, opts.Operations)
+}
+
+// associationValidators builds the validators of the children of the
+// associations of opts, nil for those not validated
+func associationValidators[T any](opts CRUDOptions) map[string]*validator.StructValidator {
+	associations, err := repositories.Associations[T](opts.Associations...)
+	if err != nil {
+		panic(err)
+	}
+	validators := map[string]*validator.StructValidator{}
+	for _, a := range associations {
+		v := loadValidator(opts.AssociationSchemas[a.JSONName])
+		if v == nil && opts.SchemaFromTags {
+			v = validator.NewStructValidator()
+			if err := v.LoadSchemaFromStruct(reflect.New(a.Model).Interface()); err != nil {
+				panic(fmt.Sprintf("building schema of %s from tags: %v", a.Model.Name(), err))
+			}
+		}
+		validators[a.JSONName] = v
+	}
+	return validators
+}
+
+// loadValidator builds a validator for the given schema file, nil when no file is given
+func loadValidator(schemaPath string) *validator.StructValidator {
+	if schemaPath == "" {
+		return nil
+	}
+	v := validator.NewStructValidator()
+	if err := v.LoadSchemaFromFile(schemaPath); err != nil {
+		panic(fmt.Sprintf("loading schema %s: %v", schemaPath, err))
+	}
+	return v
+}
+
+// withMiddlewares returns the middlewares configured for op followed by the
+// handler, after the transaction middleware for transactional writes
+func withMiddlewares(opts CRUDOptions, op Operation, handler fiber.Handler) []fiber.Handler {
+	chain := []fiber.Handler{}
+	if opts.Transactional && op.writes() {
+		chain = append(chain, Transactional())
+	}
+	chain = append(chain, opts.Middlewares[op]...)
+	return append(chain, handler)
 }
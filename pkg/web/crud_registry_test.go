@@ -0,0 +1,159 @@
+package web
+
+import (
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/model"
+	"github.com/arturoeanton/go-struc2fiber/pkg/repositories"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB points repositories.DB to a new in-memory database holding
+// the tables of the models
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: opens a distinct database
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(&model.InternalUser{}, &model.Skill{}, &model.DayOff{}, &model.Connect{}, &model.Apartment{}, &model.Checkin{}); err != nil {
+		t.Fatal(err)
+	}
+	repositories.DB = db
+	return db
+}
+
+// isolateResources restores the registered resources when the test ends
+func isolateResources(t *testing.T) {
+	t.Helper()
+	resourcesMu.Lock()
+	saved := resources
+	resources = nil
+	resourcesMu.Unlock()
+	t.Cleanup(func() {
+		resourcesMu.Lock()
+		resources = saved
+		resourcesMu.Unlock()
+	})
+}
+
+// routes returns the routes of app as "METHOD path", HEAD routes excluded
+func routes(app *fiber.App) []string {
+	result := []string{}
+	for _, route := range app.GetRoutes(true) {
+		if route.Method == fiber.MethodHead {
+			continue
+		}
+		result = append(result, route.Method+" "+route.Path)
+	}
+	slices.Sort(result)
+	return result
+}
+
+func TestRegisterCRUDWithOptionsRoutes(t *testing.T) {
+	openTestDB(t)
+
+	tests := []struct {
+		name string
+		opts CRUDOptions
+		want []string
+	}{
+		{"defaults", CRUDOptions{}, []string{
+			"DELETE /skill/:id",
+			"GET /skill",
+			"GET /skill/:id",
+			"GET /skill/_schema",
+			"PATCH /skill/:id",
+			"POST /skill",
+			"PUT /skill/:id",
+		}},
+		// The bulk routes are only exposed on demand
+		{"bulk", CRUDOptions{Operations: append([]Operation{OpList}, BulkOperations...)}, []string{
+			"DELETE /skill",
+			"GET /skill",
+			"PATCH /skill/_bulk",
+			"POST /skill/_bulk",
+		}},
+		{"read only", CRUDOptions{Operations: ReadOnlyOperations}, []string{
+			"GET /skill",
+			"GET /skill/:id",
+		}},
+		{"some operations", CRUDOptions{Operations: []Operation{OpCreate, OpDelete}}, []string{
+			"DELETE /skill/:id",
+			"POST /skill",
+		}},
+		{"prefix and id param", CRUDOptions{Operations: []Operation{OpGet, OpUpdate}, Prefix: "/api/v1/", IDParam: "skill_id"}, []string{
+			"GET /api/v1/skill/:skill_id",
+			"PUT /api/v1/skill/:skill_id",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolateResources(t)
+			app := fiber.New()
+			RegisterCRUDWithOptions(app, "skill", model.Skill{}, tt.opts)
+			if got := routes(app); !slices.Equal(got, tt.want) {
+				t.Fatalf("routes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterCRUDWithOptionsMiddlewares(t *testing.T) {
+	openTestDB(t)
+	isolateResources(t)
+
+	app := fiber.New()
+	RegisterCRUDWithOptions(app, "skill", model.Skill{}, CRUDOptions{
+		Operations: []Operation{OpList, OpDelete},
+		Middlewares: map[Operation][]fiber.Handler{
+			OpDelete: {func(c *fiber.Ctx) error {
+				return c.SendStatus(fiber.StatusForbidden)
+			}},
+		},
+	})
+
+	tests := []struct {
+		method string
+		target string
+		want   int
+	}{
+		{"GET", "/skill", fiber.StatusOK},
+		{"DELETE", "/skill/1", fiber.StatusForbidden},
+	}
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest(tt.method, tt.target, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.target, resp.StatusCode, tt.want)
+		}
+	}
+}
+
+func TestRegisterCRUDUnknownOperation(t *testing.T) {
+	openTestDB(t)
+	isolateResources(t)
+
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(r.(string), `unknown operation "archive"`) {
+			t.Fatalf("panic = %v, want unknown operation", r)
+		}
+	}()
+	RegisterCRUDWithOptions(fiber.New(), "skill", model.Skill{}, CRUDOptions{Operations: []Operation{"archive"}})
+}
@@ -0,0 +1,71 @@
+package web
+
+import (
+	"embed"
+	"html"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+//go:embed docs.html
+var docsPage string
+
+// swaggerUI holds the Swagger UI assets served along with the docs page, see
+// swagger-ui/README.md
+//
+//go:embed swagger-ui/swagger-ui-bundle.js swagger-ui/swagger-ui.css swagger-ui/favicon-32x32.png
+var swaggerUI embed.FS
+
+// DocsOptions configures the routes registered by RegisterDocs
+type DocsOptions struct {
+	// Info describes the API in the OpenAPI document
+	Info OpenAPIInfo
+	// SpecPath serves the OpenAPI document, "/openapi.json" by default
+	SpecPath string
+	// UIPath serves the HTML documentation, "/docs" by default, and its
+	// assets under it
+	UIPath string
+}
+
+// RegisterDocs serves the OpenAPI document of the registered resources and a
+// Swagger UI page browsing it. The Swagger UI assets are embedded, so the page
+// needs no external resources. Register it after the resources are
+// registered, or at any time: the document is built on each request.
+func RegisterDocs(app fiber.Router, opts DocsOptions) {
+	if opts.SpecPath == "" {
+		opts.SpecPath = "/openapi.json"
+	}
+	if opts.UIPath == "" {
+		opts.UIPath = "/docs"
+	}
+
+	app.Get(opts.SpecPath, func(c *fiber.Ctx) error {
+		return c.JSON(OpenAPI(opts.Info))
+	})
+
+	title := opts.Info.Title
+	if title == "" {
+		title = "API"
+	}
+	page := strings.NewReplacer(
+		"{{title}}", html.EscapeString(title),
+		"{{assets}}", html.EscapeString(routerPrefix(app)+opts.UIPath),
+		"{{spec}}", strconv.Quote(routerPrefix(app)+opts.SpecPath),
+	).Replace(docsPage)
+	app.Get(opts.UIPath, func(c *fiber.Ctx) error {
+		c.Type("html", "utf-8")
+		return c.SendString(page)
+	})
+	app.Get(opts.UIPath+"/:asset", func(c *fiber.Ctx) error {
+		data, err := swaggerUI.ReadFile("swagger-ui/" + c.Params("asset"))
+		if err != nil {
+			return fiber.ErrNotFound
+		}
+		c.Type(path.Ext(c.Params("asset")))
+		c.Set(fiber.HeaderCacheControl, "public, max-age=86400")
+		return c.Send(data)
+	})
+}
@@ -0,0 +1,499 @@
+package web
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/handlers"
+	"github.com/arturoeanton/go-struc2fiber/pkg/repositories"
+	"github.com/arturoeanton/go-struc2fiber/pkg/validator"
+)
+
+// jsonObject is a node of the generated OpenAPI document
+type jsonObject = map[string]interface{}
+
+// OpenAPIInfo describes the API in the generated document
+type OpenAPIInfo struct {
+	Title       string
+	Version     string
+	Description string
+}
+
+// OpenAPI builds an OpenAPI 3.1 document describing the resources registered
+// so far. Model schemas come from the json tags of the structs, pointers
+// being nullable, and request bodies add the rules of the validation schemas.
+func OpenAPI(info OpenAPIInfo) jsonObject {
+	if info.Title == "" {
+		info.Title = "API"
+	}
+	if info.Version == "" {
+		info.Version = "1.0.0"
+	}
+
+	b := &openAPIBuilder{schemas: jsonObject{}, paths: jsonObject{}}
+	tags := []interface{}{}
+	for _, r := range Resources() {
+		b.resource(r)
+		tags = append(tags, jsonObject{"name": r.Name})
+	}
+	b.schemas["Problem"] = problemSchema
+	b.schemas["JSONPatch"] = jsonPatchSchema
+	b.schemas["BulkResult"] = bulkResultSchema
+
+	infoObject := jsonObject{"title": info.Title, "version": info.Version}
+	if info.Description != "" {
+		infoObject["description"] = info.Description
+	}
+	return jsonObject{
+		"openapi":    "3.1.0",
+		"info":       infoObject,
+		"tags":       tags,
+		"paths":      b.paths,
+		"components": jsonObject{"schemas": b.schemas},
+	}
+}
+
+// openAPIBuilder accumulates the paths and component schemas of the document
+type openAPIBuilder struct {
+	schemas jsonObject
+	paths   jsonObject
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// ref returns a reference to the component schema of a named struct type,
+// adding it on first use
+func (b *openAPIBuilder) ref(typ reflect.Type) jsonObject {
+	name := typ.Name()
+	if _, ok := b.schemas[name]; !ok {
+		// Reserve the name first so recursive types end in a reference
+		b.schemas[name] = jsonObject{}
+		b.schemas[name] = b.structSchema(typ)
+	}
+	return jsonObject{"$ref": "#/components/schemas/" + name}
+}
+
+// typeSchema returns the JSON Schema of a Go type as encoded by encoding/json
+func (b *openAPIBuilder) typeSchema(typ reflect.Type) jsonObject {
+	nullable := false
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+		nullable = true
+	}
+
+	var s jsonObject
+	switch typ.Kind() {
+	case reflect.String:
+		s = jsonObject{"type": "string"}
+	case reflect.Bool:
+		s = jsonObject{"type": "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		s = jsonObject{"type": "integer", "format": "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		s = jsonObject{"type": "integer", "format": "int32"}
+	case reflect.Float32:
+		s = jsonObject{"type": "number", "format": "float"}
+	case reflect.Float64:
+		s = jsonObject{"type": "number", "format": "double"}
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			s = jsonObject{"type": "string", "contentEncoding": "base64"}
+		} else {
+			s = jsonObject{"type": "array", "items": b.typeSchema(typ.Elem())}
+		}
+	case reflect.Map:
+		s = jsonObject{"type": "object", "additionalProperties": b.typeSchema(typ.Elem())}
+	case reflect.Struct:
+		switch {
+		case typ == timeType:
+			s = jsonObject{"type": "string", "format": "date-time"}
+		case typ.Name() != "":
+			s = b.ref(typ)
+		default:
+			s = b.structSchema(typ)
+		}
+	default:
+		s = jsonObject{}
+	}
+
+	if nullable {
+		if t, ok := s["type"].(string); ok {
+			s["type"] = []string{t, "null"}
+		} else if _, ok := s["$ref"]; ok {
+			s = jsonObject{"anyOf": []interface{}{s, jsonObject{"type": "null"}}}
+		}
+	}
+	return s
+}
+
+// structSchema describes the JSON object encoding a struct
+func (b *openAPIBuilder) structSchema(typ reflect.Type) jsonObject {
+	properties := jsonObject{}
+	b.addProperties(typ, properties)
+	return jsonObject{"type": "object", "properties": properties}
+}
+
+func (b *openAPIBuilder) addProperties(typ reflect.Type, properties jsonObject) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := strings.Split(field.Tag.Get("json"), ",")[0]
+		if tag == "-" {
+			continue
+		}
+		if field.Anonymous && tag == "" {
+			embedded := field.Type
+			for embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				b.addProperties(embedded, properties)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		name := field.Name
+		if tag != "" {
+			name = tag
+		}
+		properties[name] = b.typeSchema(field.Type)
+	}
+}
+
+// bodySchema is the schema of create or update bodies: the model with the
+// rules of the validation schema, if any
+func (b *openAPIBuilder) bodySchema(r *Resource, name string, schema *validator.ValidationSchema) jsonObject {
+	model := b.typeSchema(r.Model)
+	if schema == nil {
+		return model
+	}
+	rules := validator.ToJSONSchema(schema)
+	rules.Schema = ""
+	b.schemas[name] = jsonObject{"allOf": []interface{}{model, rules}}
+	return jsonObject{"$ref": "#/components/schemas/" + name}
+}
+
+// resource adds the paths of a registered resource
+func (b *openAPIBuilder) resource(r *Resource) {
+	model := b.typeSchema(r.Model)
+	createBody := b.bodySchema(r, bodySchemaName(r, "Create"), r.CreateSchema)
+	updateBody := b.bodySchema(r, bodySchemaName(r, "Update"), r.UpdateSchema)
+
+	collection := jsonObject{}
+	item := jsonObject{}
+	var schema jsonObject
+	idParam := parameter(r.IDParam, "path", "ID of the "+r.Name, jsonObject{"type": "integer"})
+	idParam["required"] = true
+	readParams := b.readParameters(r)
+
+	if r.Has(OpList) {
+		params := append(b.listParameters(r), readParams...)
+		collection["get"] = operation(r, "list", "List "+r.Name, params, nil, jsonObject{
+			"200": jsonObject{
+				"description": "The items of the page, wrapped with the cursors when listing by cursor",
+				"headers": jsonObject{
+					"X-Total-Count": jsonObject{"description": "Number of items matching the filters", "schema": jsonObject{"type": "integer"}},
+					"Link":          jsonObject{"description": "RFC 8288 links to the first, previous, next and last pages", "schema": jsonObject{"type": "string"}},
+				},
+				"content": jsonContent(jsonObject{"oneOf": []interface{}{
+					jsonObject{"type": "array", "items": model},
+					jsonObject{"type": "object", "properties": jsonObject{
+						"data":        jsonObject{"type": "array", "items": model},
+						"next_cursor": jsonObject{"type": []string{"string", "null"}},
+						"prev_cursor": jsonObject{"type": []string{"string", "null"}},
+					}},
+				}}),
+			},
+			"400": problemResponse("Invalid query string"),
+		})
+	}
+	if r.Has(OpCreate) {
+		collection["post"] = operation(r, "create", "Create a "+r.Name, nil, requestBody(jsonContent(createBody)), jsonObject{
+			"200": jsonObject{"description": "ID of the new item", "content": jsonContent(jsonObject{"type": "integer"})},
+			"400": problemResponse("Malformed body"),
+			"409": problemResponse("Conflicting item"),
+			"422": problemResponse("Validation failed"),
+		})
+	}
+	if r.Has(OpGet) {
+		item["get"] = operation(r, "get", "Get a "+r.Name, append([]interface{}{idParam}, readParams...), nil, jsonObject{
+			"200": jsonObject{"description": "The item", "content": jsonContent(model)},
+			"400": problemResponse("Invalid query string"),
+			"404": problemResponse("Item not found"),
+		})
+	}
+	if r.Has(OpUpdate) {
+		item["put"] = operation(r, "update", "Replace a "+r.Name, []interface{}{idParam}, requestBody(jsonContent(updateBody)), jsonObject{
+			"204": jsonObject{"description": "Updated"},
+			"400": problemResponse("Malformed body"),
+			"404": problemResponse("Item not found"),
+			"422": problemResponse("Validation failed"),
+		})
+	}
+	if r.Has(OpPatch) {
+		item["patch"] = operation(r, "patch", "Update part of a "+r.Name, []interface{}{idParam}, requestBody(jsonObject{
+			"application/json":          jsonObject{"schema": model},
+			handlers.MIMEMergePatchJSON: jsonObject{"schema": model},
+			handlers.MIMEJSONPatchJSON:  jsonObject{"schema": jsonObject{"$ref": "#/components/schemas/JSONPatch"}},
+		}), jsonObject{
+			"204": jsonObject{"description": "Updated"},
+			"400": problemResponse("Malformed body or patch"),
+			"404": problemResponse("Item not found"),
+			"409": problemResponse("Patch does not apply to the item"),
+			"415": problemResponse("Unsupported patch format"),
+			"422": problemResponse("Validation failed"),
+		})
+	}
+	if r.Has(OpDelete) {
+		item["delete"] = operation(r, "delete", "Delete a "+r.Name, []interface{}{idParam}, nil, jsonObject{
+			"200": jsonObject{"description": "Deleted", "content": jsonContent(jsonObject{
+				"type":       "object",
+				"properties": jsonObject{"rows_affected": jsonObject{"type": "integer"}},
+			})},
+			"404": problemResponse("Item not found"),
+		})
+	}
+	if r.Has(OpSchema) {
+		param := parameter("for", "query", "Operation the schema validates", jsonObject{"enum": []string{"create", "update"}, "default": "create"})
+		schema = operation(r, "schema", "JSON Schema of the "+r.Name+" bodies", []interface{}{param}, nil, jsonObject{
+			"200": jsonObject{"description": "The JSON Schema", "content": jsonObject{handlers.MIMESchemaJSON: jsonObject{"schema": jsonObject{"type": "object"}}}},
+			"404": problemResponse("No validation schema"),
+		})
+	}
+
+	bulk := b.bulkOperations(r, model, createBody, collection)
+
+	path := routeParam.ReplaceAllString(r.Path, "{$1}")
+	if r.Has(OpSchema) {
+		b.paths[path+"/_schema"] = jsonObject{"get": schema}
+	}
+	if len(bulk) > 0 {
+		b.paths[path+"/_bulk"] = bulk
+	}
+	if len(collection) > 0 {
+		b.paths[path] = collection
+	}
+	if len(item) > 0 {
+		b.paths[path+"/{"+r.IDParam+"}"] = item
+	}
+	// The parent parameter is shared by the operations of nested resources
+	if r.ParentParam != "" {
+		parentParam := parameter(r.ParentParam, "path", "ID of the parent "+r.Parent, jsonObject{"type": "integer"})
+		parentParam["required"] = true
+		for _, op := range collection {
+			responses := op.(jsonObject)["responses"].(jsonObject)
+			responses["404"] = problemResponse("Parent " + r.Parent + " not found")
+		}
+		for _, p := range []string{path + "/_schema", path + "/_bulk", path, path + "/{" + r.IDParam + "}"} {
+			if pathItem, ok := b.paths[p].(jsonObject); ok {
+				pathItem["parameters"] = []interface{}{parentParam}
+			}
+		}
+	}
+}
+
+// bulkOperations returns the bulk create and update operations, adding the
+// bulk delete to collection
+func (b *openAPIBuilder) bulkOperations(r *Resource, model, createBody, collection jsonObject) jsonObject {
+	mode := parameter("mode", "query", "atomic writes every item or none of them, best_effort writes the items it can", jsonObject{
+		"enum":    []string{string(handlers.BulkAtomic), string(handlers.BulkBestEffort)},
+		"default": string(handlers.BulkAtomic),
+	})
+	responses := func() jsonObject {
+		result := jsonContent(jsonObject{"$ref": "#/components/schemas/BulkResult"})
+		return jsonObject{
+			"200": jsonObject{"description": "Every item succeeded", "content": result},
+			"207": jsonObject{"description": "Some items failed, see their status", "content": result},
+			"400": problemResponse("Malformed request"),
+			"4XX": jsonObject{"description": "No item was written, see the status of the items", "content": result},
+		}
+	}
+	items := func(schema jsonObject) jsonObject {
+		return requestBody(jsonContent(jsonObject{"type": "array", "items": schema, "minItems": 1, "maxItems": handlers.MaxBulkItems}))
+	}
+
+	bulk := jsonObject{}
+	if r.Has(OpBulkCreate) {
+		bulk["post"] = operation(r, "bulk_create", "Create several "+r.Name, []interface{}{mode}, items(createBody), responses())
+	}
+	if r.Has(OpBulkUpdate) {
+		body := jsonObject{"allOf": []interface{}{model, jsonObject{"required": []string{"id"}}}}
+		bulk["patch"] = operation(r, "bulk_update", "Update part of several "+r.Name, []interface{}{mode}, items(body), responses())
+	}
+	if r.Has(OpBulkDelete) {
+		ids := parameter("ids", "query", "Comma separated IDs of the items to delete", jsonObject{"type": "string"})
+		ids["required"] = true
+		collection["delete"] = operation(r, "bulk_delete", "Delete several "+r.Name, []interface{}{ids, mode}, nil, responses())
+	}
+	return bulk
+}
+
+// routeParam matches the parameters of Fiber routes, like :user_id
+var routeParam = regexp.MustCompile(`:([A-Za-z0-9_]+)`)
+
+// listParameters describes the pagination, filter and sort parameters
+func (b *openAPIBuilder) listParameters(r *Resource) []interface{} {
+	integer := jsonObject{"type": "integer", "minimum": 0}
+	params := []interface{}{
+		parameter("page", "query", "Page number, starting at 1", integer),
+		parameter("per_page", "query", "Items per page, at most "+strconv.Itoa(handlers.MaxPerPage), integer),
+		parameter("limit", "query", "Maximum number of items, instead of per_page", integer),
+		parameter("offset", "query", "Number of items to skip, instead of page", integer),
+		parameter("cursor", "query", "Opaque cursor returned by a previous listing, empty for the first page", jsonObject{"type": "string"}),
+	}
+	if len(r.Sortable) > 0 {
+		params = append(params, parameter("sort", "query",
+			"Comma separated fields to sort by, prefixed with - for descending order: "+strings.Join(r.Sortable, ", "),
+			jsonObject{"type": "string"}))
+	}
+
+	operators := jsonObject{}
+	for _, op := range repositories.Operators {
+		operators[string(op)] = jsonObject{"type": "string"}
+	}
+	for _, field := range r.Filterable {
+		param := parameter(field, "query", "Filters on "+field+", either "+field+"=value or "+field+"[operator]=value", jsonObject{
+			"type":       "object",
+			"properties": operators,
+		})
+		param["style"] = "deepObject"
+		param["explode"] = true
+		params = append(params, param)
+	}
+	return params
+}
+
+// readParameters describes the sparse fieldset and include parameters
+func (b *openAPIBuilder) readParameters(r *Resource) []interface{} {
+	params := []interface{}{
+		parameter("fields", "query", "Comma separated fields to return", jsonObject{"type": "string"}),
+	}
+	if len(r.Includes) > 0 {
+		params = append(params, parameter("include", "query",
+			"Comma separated associations to load: "+strings.Join(r.Includes, ", "),
+			jsonObject{"type": "string"}))
+	}
+	return params
+}
+
+func operation(r *Resource, op, summary string, params []interface{}, body jsonObject, responses jsonObject) jsonObject {
+	o := jsonObject{
+		"operationId": op + "_" + operationName(r),
+		"summary":     summary,
+		"tags":        []string{r.Name},
+		"responses":   responses,
+	}
+	if len(params) > 0 {
+		o["parameters"] = params
+	}
+	if body != nil {
+		o["requestBody"] = body
+	}
+	return o
+}
+
+// operationName identifies the resource in operation IDs, prefixed with the
+// parent for nested resources
+func operationName(r *Resource) string {
+	if r.Parent != "" {
+		return r.Parent + "_" + r.Name
+	}
+	return r.Name
+}
+
+// bodySchemaName names a body schema of a resource after its route, like
+// "InternalUsersSkillsCreate", as resources sharing a model may validate
+// their bodies with different schemas
+func bodySchemaName(r *Resource, kind string) string {
+	var name strings.Builder
+	for _, word := range strings.FieldsFunc(operationName(r), func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c)
+	}) {
+		name.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return name.String() + kind
+}
+
+func parameter(name, in, description string, schema jsonObject) jsonObject {
+	return jsonObject{"name": name, "in": in, "description": description, "schema": schema}
+}
+
+func requestBody(content jsonObject) jsonObject {
+	return jsonObject{"required": true, "content": content}
+}
+
+func jsonContent(schema jsonObject) jsonObject {
+	return jsonObject{"application/json": jsonObject{"schema": schema}}
+}
+
+func problemResponse(description string) jsonObject {
+	return jsonObject{
+		"description": description,
+		"content":     jsonObject{handlers.MIMEProblemJSON: jsonObject{"schema": jsonObject{"$ref": "#/components/schemas/Problem"}}},
+	}
+}
+
+// problemSchema describes the handlers.Problem error bodies
+var problemSchema = jsonObject{
+	"type": "object",
+	"properties": jsonObject{
+		"type":     jsonObject{"type": "string"},
+		"title":    jsonObject{"type": "string"},
+		"status":   jsonObject{"type": "integer"},
+		"detail":   jsonObject{"type": "string"},
+		"instance": jsonObject{"type": "string"},
+		"code":     jsonObject{"type": "string"},
+		"errors": jsonObject{"type": "array", "items": jsonObject{
+			"type": "object",
+			"properties": jsonObject{
+				"field":   jsonObject{"type": "string"},
+				"rule":    jsonObject{"type": "string"},
+				"code":    jsonObject{"type": "string"},
+				"params":  jsonObject{"type": "object"},
+				"message": jsonObject{"type": "string"},
+			},
+		}},
+	},
+	"required": []string{"type", "title", "status"},
+}
+
+// bulkResultSchema describes the handlers.BulkResult bodies
+var bulkResultSchema = jsonObject{
+	"type": "object",
+	"properties": jsonObject{
+		"mode":      jsonObject{"enum": []string{string(handlers.BulkAtomic), string(handlers.BulkBestEffort)}},
+		"succeeded": jsonObject{"type": "integer"},
+		"failed":    jsonObject{"type": "integer"},
+		"items": jsonObject{"type": "array", "items": jsonObject{
+			"type": "object",
+			"properties": jsonObject{
+				"index":  jsonObject{"type": "integer"},
+				"id":     jsonObject{"type": "integer"},
+				"status": jsonObject{"type": "integer"},
+				"code":   jsonObject{"type": "string"},
+				"detail": jsonObject{"type": "string"},
+				"errors": problemSchema["properties"].(jsonObject)["errors"],
+			},
+		}},
+	},
+}
+
+// jsonPatchSchema describes RFC 6902 bodies
+var jsonPatchSchema = jsonObject{
+	"type": "array",
+	"items": jsonObject{
+		"type":     "object",
+		"required": []string{"op", "path"},
+		"properties": jsonObject{
+			"op":    jsonObject{"enum": []string{"add", "remove", "replace", "move", "copy", "test"}},
+			"path":  jsonObject{"type": "string"},
+			"from":  jsonObject{"type": "string"},
+			"value": jsonObject{},
+		},
+	},
+}
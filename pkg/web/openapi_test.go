@@ -0,0 +1,192 @@
+package web
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/model"
+	"github.com/gofiber/fiber/v2"
+)
+
+// registerSkills registers the skills at /skill, bulk routes included, and
+// under their user, both validated with the schemas of the schemas directory
+func registerSkills(t *testing.T, app fiber.Router) {
+	t.Helper()
+	isolateResources(t)
+	openTestDB(t)
+	RegisterCRUDWithOptions(app, "skill", model.Skill{}, CRUDOptions{
+		CreateSchema: "../../schemas/generated/skill.yaml",
+		UpdateSchema: "../../schemas/update_skill.yaml",
+		Operations:   append(slices.Clone(AllOperations), BulkOperations...),
+	})
+	RegisterNestedCRUD(app, "internal_users", model.InternalUser{}, "skills", model.Skill{}, CRUDOptions{
+		CreateSchema: "../../schemas/generated/skill.yaml",
+	})
+}
+
+// get runs a GET request against app and returns the response with its body
+func get(t *testing.T, app *fiber.App, target string) (*http.Response, string) {
+	t.Helper()
+	resp, err := app.Test(httptest.NewRequest("GET", target, nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(body)
+}
+
+// lookup follows a path of keys in a decoded document
+func lookup(doc interface{}, keys ...string) interface{} {
+	for _, key := range keys {
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		doc = obj[key]
+	}
+	return doc
+}
+
+// keys returns the sorted keys of a decoded object
+func keys(doc interface{}) []string {
+	result := []string{}
+	obj, _ := doc.(map[string]interface{})
+	for key := range obj {
+		result = append(result, key)
+	}
+	slices.Sort(result)
+	return result
+}
+
+func TestOpenAPI(t *testing.T) {
+	registerSkills(t, fiber.New())
+	// Decode the document as clients see it
+	data, err := json.Marshal(OpenAPI(OpenAPIInfo{Title: "Skills"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := lookup(doc, "openapi"); got != "3.1.0" {
+		t.Errorf("openapi = %v", got)
+	}
+	if got := lookup(doc, "info", "title"); got != "Skills" {
+		t.Errorf("title = %v", got)
+	}
+
+	paths := map[string][]string{
+		"/skill":                           {"delete", "get", "post"},
+		"/skill/_bulk":                     {"patch", "post"},
+		"/skill/_schema":                   {"get"},
+		"/skill/{id}":                      {"delete", "get", "patch", "put"},
+		"/internal_users/{user_id}/skills": {"get", "parameters", "post"},
+		"/internal_users/{user_id}/skills/_schema": {"get", "parameters"},
+		"/internal_users/{user_id}/skills/{id}":    {"delete", "get", "parameters", "patch", "put"},
+	}
+	gotPaths := map[string][]string{}
+	for _, path := range keys(lookup(doc, "paths")) {
+		gotPaths[path] = keys(lookup(doc, "paths", path))
+	}
+	if !reflect.DeepEqual(gotPaths, paths) {
+		t.Errorf("paths = %v, want %v", gotPaths, paths)
+	}
+
+	// Body schemas are named after the route, the skills of both routes
+	// being validated with different schemas
+	schemas := []string{"BulkResult", "InternalUsersSkillsCreate", "InternalUsersSkillsUpdate", "JSONPatch", "Problem", "Skill", "SkillCreate", "SkillUpdate"}
+	if got := keys(lookup(doc, "components", "schemas")); !reflect.DeepEqual(got, schemas) {
+		t.Errorf("schemas = %v, want %v", got, schemas)
+	}
+	bodies := []struct {
+		path, method, schema string
+	}{
+		{"/skill", "post", "SkillCreate"},
+		{"/skill/{id}", "put", "SkillUpdate"},
+		{"/internal_users/{user_id}/skills", "post", "InternalUsersSkillsCreate"},
+		{"/internal_users/{user_id}/skills/{id}", "put", "InternalUsersSkillsUpdate"},
+	}
+	for _, body := range bodies {
+		ref := lookup(doc, "paths", body.path, body.method, "requestBody", "content", "application/json", "schema", "$ref")
+		if ref != "#/components/schemas/"+body.schema {
+			t.Errorf("%s %s body = %v, want %s", body.method, body.path, ref, body.schema)
+		}
+	}
+	// Body schemas add the rules of the validation schema to the model
+	for _, name := range []string{"SkillCreate", "SkillUpdate", "InternalUsersSkillsCreate"} {
+		allOf, _ := lookup(doc, "components", "schemas", name, "allOf").([]interface{})
+		if len(allOf) != 2 || lookup(allOf[0], "$ref") != "#/components/schemas/Skill" || lookup(allOf[1], "properties", "name") == nil {
+			t.Errorf("%s = %v", name, lookup(doc, "components", "schemas", name))
+		}
+	}
+
+	// Operation IDs are unique
+	seen := map[string]string{}
+	for path := range gotPaths {
+		for _, method := range gotPaths[path] {
+			id, _ := lookup(doc, "paths", path, method, "operationId").(string)
+			if method == "parameters" {
+				continue
+			}
+			if id == "" || seen[id] != "" {
+				t.Errorf("%s %s: operationId %q, also used by %s", method, path, id, seen[id])
+			}
+			seen[id] = method + " " + path
+		}
+	}
+}
+
+func TestRegisterDocs(t *testing.T) {
+	app := fiber.New()
+	api := app.Group("/api")
+	registerSkills(t, api)
+	RegisterDocs(api, DocsOptions{Info: OpenAPIInfo{Title: "Skills <API>"}})
+
+	tests := []struct {
+		target      string
+		status      int
+		contentType string
+		contains    string
+	}{
+		{"/api/openapi.json", fiber.StatusOK, fiber.MIMEApplicationJSON, `"/api/skill/{id}"`},
+		{"/api/docs", fiber.StatusOK, fiber.MIMETextHTMLCharsetUTF8, `url: "/api/openapi.json"`},
+		{"/api/docs/swagger-ui-bundle.js", fiber.StatusOK, "javascript", "SwaggerUIBundle"},
+		{"/api/docs/swagger-ui.css", fiber.StatusOK, "text/css", ".swagger-ui"},
+		{"/api/docs/favicon-32x32.png", fiber.StatusOK, "image/png", ""},
+		{"/api/docs/README.md", fiber.StatusNotFound, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			resp, body := get(t, app, tt.target)
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if got := resp.Header.Get(fiber.HeaderContentType); !strings.Contains(got, tt.contentType) {
+				t.Errorf("Content-Type = %q, want %q", got, tt.contentType)
+			}
+			if !strings.Contains(body, tt.contains) {
+				t.Errorf("body does not contain %q", tt.contains)
+			}
+		})
+	}
+
+	// The page loads its assets from the docs route and escapes the title
+	_, page := get(t, app, "/api/docs")
+	for _, want := range []string{`<title>Skills &lt;API&gt;</title>`, `src="/api/docs/swagger-ui-bundle.js"`, `href="/api/docs/swagger-ui.css"`} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page does not contain %s", want)
+		}
+	}
+}
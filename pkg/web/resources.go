@@ -0,0 +1,84 @@
+package web
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/validator"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Resource describes a resource registered with RegisterCRUD
+type Resource struct {
+	// Name is the resource name, the last segment of Path
+	Name string
+	// Path is the full collection path, e.g. "/api/v1/skill", with the
+	// parent parameter for nested resources, e.g. "/internal_users/:user_id/skills"
+	Path string
+	// Parent is the parent resource name of nested resources, and ParentParam
+	// the route parameter holding the parent ID
+	Parent      string
+	ParentParam string
+	// IDParam is the name of the route parameter holding the item ID
+	IDParam string
+	// Model is the struct type of the items
+	Model reflect.Type
+	// Operations are the routes exposed
+	Operations []Operation
+	// CreateSchema and UpdateSchema validate the bodies, nil when not validated
+	CreateSchema *validator.ValidationSchema
+	UpdateSchema *validator.ValidationSchema
+	// Filterable, Sortable and Includes are the JSON fields and associations
+	// usable in the query string of the listings
+	Filterable []string
+	Sortable   []string
+	Includes   []string
+}
+
+// Has reports whether the resource exposes op
+func (r *Resource) Has(op Operation) bool {
+	for _, o := range r.Operations {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	resourcesMu sync.RWMutex
+	resources   []*Resource
+)
+
+// Resources returns the resources registered so far, in registration order
+func Resources() []*Resource {
+	resourcesMu.RLock()
+	defer resourcesMu.RUnlock()
+	return append([]*Resource{}, resources...)
+}
+
+func addResource(r *Resource) {
+	for r.Model.Kind() == reflect.Ptr {
+		r.Model = r.Model.Elem()
+	}
+	resourcesMu.Lock()
+	defer resourcesMu.Unlock()
+	resources = append(resources, r)
+}
+
+// routerPrefix returns the path prefix of a router created with Group
+func routerPrefix(router fiber.Router) string {
+	if group, ok := router.(*fiber.Group); ok {
+		return strings.TrimSuffix(group.Prefix, "/")
+	}
+	return ""
+}
+
+// schemaOf returns the schema of a validator, nil when there is none
+func schemaOf(v *validator.StructValidator) *validator.ValidationSchema {
+	if v == nil {
+		return nil
+	}
+	return v.GetSchema()
+}
@@ -0,0 +1,49 @@
+package web
+
+import (
+	"context"
+	"errors"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/repositories"
+	"github.com/gofiber/fiber/v2"
+)
+
+// errRollback rolls back the transaction of a request answered with an error
+var errRollback = errors.New("request failed")
+
+// Transactional returns a middleware running the rest of the request in one
+// transaction, see repositories.WithTx. The transaction is rolled back when
+// a handler returns an error or answers with an error status.
+func Transactional() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		parent := c.UserContext()
+		var handlerErr error
+		err := repositories.WithTx(parent, func(ctx context.Context) error {
+			c.SetUserContext(ctx)
+			defer c.SetUserContext(parent)
+			if handlerErr = c.Next(); handlerErr != nil {
+				return handlerErr
+			}
+			if c.Response().StatusCode() >= fiber.StatusBadRequest {
+				return errRollback
+			}
+			return nil
+		})
+		if handlerErr != nil {
+			return handlerErr
+		}
+		if err != nil && !errors.Is(err, errRollback) {
+			return err
+		}
+		return nil
+	}
+}
+
+// writes reports whether op changes data
+func (op Operation) writes() bool {
+	switch op {
+	case OpCreate, OpUpdate, OpPatch, OpDelete, OpBulkCreate, OpBulkUpdate, OpBulkDelete:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package web
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/arturoeanton/go-struc2fiber/pkg/model"
+	"github.com/arturoeanton/go-struc2fiber/pkg/repositories"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestTransactional(t *testing.T) {
+	db := openTestDB(t)
+	app := fiber.New()
+	// Each route writes a skill then answers as its name says
+	write := func(c *fiber.Ctx) error {
+		if _, ok := repositories.TxFrom(c.UserContext()); !ok {
+			return errors.New("no transaction")
+		}
+		return repositories.Conn(c.UserContext()).Create(&model.Skill{UserID: 1, Name: c.Path()}).Error
+	}
+	app.Post("/ok", Transactional(), func(c *fiber.Ctx) error {
+		if err := write(c); err != nil {
+			return err
+		}
+		return c.SendStatus(fiber.StatusCreated)
+	})
+	app.Post("/unprocessable", Transactional(), func(c *fiber.Ctx) error {
+		if err := write(c); err != nil {
+			return err
+		}
+		return c.SendStatus(fiber.StatusUnprocessableEntity)
+	})
+	app.Post("/error", Transactional(), func(c *fiber.Ctx) error {
+		if err := write(c); err != nil {
+			return err
+		}
+		return fiber.NewError(fiber.StatusConflict, "taken")
+	})
+
+	tests := []struct {
+		target  string
+		status  int
+		written bool
+	}{
+		{"/ok", fiber.StatusCreated, true},
+		{"/unprocessable", fiber.StatusUnprocessableEntity, false},
+		// The error of the handler reaches the error handler of the app
+		{"/error", fiber.StatusConflict, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest("POST", tt.target, nil), -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			var count int64
+			if err := db.Model(&model.Skill{}).Where("name = ?", tt.target).Count(&count).Error; err != nil {
+				t.Fatal(err)
+			}
+			if written := count == 1; written != tt.written {
+				t.Fatalf("written = %v, want %v", written, tt.written)
+			}
+		})
+	}
+}